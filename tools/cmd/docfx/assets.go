@@ -0,0 +1,166 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	fspath "path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// assetsDir is the directory, relative to the crate output directory, where
+// we copy any local files referenced in the documentation.
+const assetsDir = "assets"
+
+var htmlImageSource = regexp.MustCompile(`<img\s[^>]*\bsrc\s*=\s*["']([^"']+)["']`)
+
+// isLocalAsset returns true if `destination` is a link to a local file.
+//
+// Rustdoc intra-doc links, such as `crate::model::Secret` or `Secret`, and
+// links to rustdoc pages, such as `struct.Secret.html`, are not assets.
+func isLocalAsset(destination string, isImage bool) bool {
+	if destination == "" || strings.HasPrefix(destination, "#") || strings.HasPrefix(destination, "/") {
+		return false
+	}
+	if u, err := url.Parse(destination); err != nil || u.Scheme != "" || u.Host != "" {
+		return false
+	}
+	if strings.Contains(destination, "::") {
+		return false
+	}
+	if isImage {
+		return true
+	}
+	ext := fspath.Ext(strings.SplitN(destination, "#", 2)[0])
+	return ext != "" && ext != ".html" && ext != ".htm"
+}
+
+// resolveAsset records a local asset referenced in the documentation and
+// returns the URL to use in the generated pages. Returns the empty string if
+// the link should not change, e.g. for links to directories.
+//
+// Returns an error if the asset is missing or outside `ctx.Boundary`.
+func (ctx *docContext) resolveAsset(destination string) (string, error) {
+	location, suffix := destination, ""
+	if idx := strings.IndexAny(location, "?#"); idx != -1 {
+		location, suffix = location[:idx], location[idx:]
+	}
+	if unescaped, err := url.PathUnescape(location); err == nil {
+		location = unescaped
+	}
+	source := filepath.Join(ctx.Root, filepath.FromSlash(location))
+	info, err := os.Stat(source)
+	if err != nil {
+		return "", fmt.Errorf("missing asset %q referenced in the documentation: %w", destination, err)
+	}
+	// The assets are published, never copy files from outside the project.
+	ok, err := withinDir(ctx.Boundary, source)
+	if err != nil {
+		return "", fmt.Errorf("error checking the location of asset %q: %w", destination, err)
+	}
+	if !ok {
+		return "", fmt.Errorf("asset %q referenced in the documentation is outside %s", destination, ctx.Boundary)
+	}
+	if info.IsDir() {
+		ctx.crate.log().Warn("links to directories are not supported, keeping the link unchanged", "link", destination)
+		return "", nil
+	}
+	// Files outside the crate directory, e.g. `../README.md`, are copied
+	// into the assets directory too.
+	var segments []string
+	for _, s := range strings.Split(fspath.Clean(location), "/") {
+		if s == ".." {
+			s = "__"
+		}
+		segments = append(segments, s)
+	}
+	target := fspath.Join(append([]string{assetsDir}, segments...)...)
+	ctx.assets[target] = source
+	if ctx.crate != nil && ctx.crate.assets != nil {
+		ctx.crate.assets.add(fspath.Clean(location))
+	}
+	return target + suffix, nil
+}
+
+// withinDir returns true if `path` is `dir` or a file in `dir`, after
+// resolving any symbolic links.
+func withinDir(dir, path string) (bool, error) {
+	resolve := func(p string) (string, error) {
+		p, err := filepath.EvalSymlinks(p)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Abs(p)
+	}
+	dir, err := resolve(dir)
+	if err != nil {
+		return false, err
+	}
+	if path, err = resolve(path); err != nil {
+		return false, err
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false, err
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)), nil
+}
+
+// copyAssets copies the assets referenced by a page into `outDir`.
+func copyAssets(assets map[string]string, outDir string) error {
+	for target, source := range assets {
+		dest := filepath.Join(outDir, filepath.FromSlash(target))
+		if err := os.MkdirAll(filepath.Dir(dest), 0777); err != nil {
+			return err
+		}
+		if err := copyFile(source, dest); err != nil {
+			return fmt.Errorf("error copying asset %s: %w", source, err)
+		}
+	}
+	return nil
+}
+
+// copyFile copies `source` to `dest`. Pages rendered concurrently may copy the
+// same asset, so the contents are written to a temporary file and then
+// atomically renamed.
+func copyFile(source, dest string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.CreateTemp(filepath.Dir(dest), filepath.Base(dest)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(out.Name()) }() // Ignore errors, the file is gone after a successful rename
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Chmod(0644); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(out.Name(), dest)
+}
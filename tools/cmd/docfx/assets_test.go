@@ -0,0 +1,150 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIsLocalAsset(t *testing.T) {
+	for _, test := range []struct {
+		destination string
+		isImage     bool
+		want        bool
+	}{
+		{"images/diagram.png", true, true},
+		{"../README.md", false, true},
+		{"examples/quickstart.rs#L10", false, true},
+		{"diagram", true, true},
+		{"Secret", false, false},
+		{"crate::model::Secret", false, false},
+		{"struct.Secret.html", false, false},
+		{"#method.send", false, false},
+		{"https://cloud.google.com/rust", false, false},
+		{"https://cloud.google.com/image.png", true, false},
+		{"xref:struct.google_cloud_secretmanager_v1.model.Secret", false, false},
+		{"/absolute/path.md", false, false},
+	} {
+		if got := isLocalAsset(test.destination, test.isImage); got != test.want {
+			t.Errorf("isLocalAsset(%q, %v) = %v, want = %v", test.destination, test.isImage, got, test.want)
+		}
+	}
+}
+
+func TestLocalAssets(t *testing.T) {
+	root := t.TempDir()
+	crateDir := filepath.Join(root, "crate")
+	for _, name := range []string{"crate/images/diagram.png", "crate/CHANGELOG.md", "README.md"} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := &crate{Location: crateDir, ProjectRoot: root}
+	ctx := c.newDocContext("")
+
+	input := `![A diagram](images/diagram.png) and <img src="images/diagram.png">.
+
+See the [changes](CHANGELOG.md#v1) and the [readme].
+
+[readme]: ../README.md`
+	want := `![A diagram](assets/images/diagram.png) and <img src="assets/images/diagram.png">.
+
+See the [changes](assets/CHANGELOG.md#v1) and the [readme].
+
+[readme]: assets/__/README.md`
+	got, err := processDocStringWithContext(input, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch in processDocStringWithContext for assets (-want, +got)\n:%s", diff)
+	}
+
+	outDir := t.TempDir()
+	if err := copyAssets(ctx.assets, outDir); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"assets/images/diagram.png", "assets/CHANGELOG.md", "assets/__/README.md"} {
+		if _, err := os.Stat(filepath.Join(outDir, name)); err != nil {
+			t.Errorf("missing asset %s in output directory: %v", name, err)
+		}
+	}
+}
+
+func TestLocalAssetsMissing(t *testing.T) {
+	c := &crate{Location: t.TempDir()}
+	input := "![A diagram](images/missing.png)"
+	if got, err := processDocStringWithContext(input, c.newDocContext("")); err == nil {
+		t.Errorf("expected an error for missing assets, got=%q", got)
+	}
+}
+
+func TestLocalAssetsOutsideProject(t *testing.T) {
+	root := t.TempDir()
+	projectRoot := filepath.Join(root, "project")
+	crateDir := filepath.Join(projectRoot, "crate")
+	if err := os.MkdirAll(filepath.Join(crateDir, "src"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "secret.txt"), filepath.Join(crateDir, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	c := &crate{Location: crateDir, ProjectRoot: projectRoot}
+	for _, input := range []string{
+		"See [the secret](../../secret.txt).",
+		"![Secret](../../secret.txt)",
+		"See [the link](link.txt).",
+	} {
+		if got, err := processDocStringWithContext(input, c.newDocContext("")); err == nil {
+			t.Errorf("expected an error for assets outside the project root in %q, got=%q", input, got)
+		}
+	}
+}
+
+func TestLocalAssetsDirectory(t *testing.T) {
+	crateDir := t.TempDir()
+	for _, dir := range []string{"examples.d", "images"} {
+		if err := os.MkdirAll(filepath.Join(crateDir, dir), 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := &crate{Location: crateDir}
+	ctx := c.newDocContext("")
+	input := "See the [examples](examples.d) and ![images](images)."
+	got, err := processDocStringWithContext(input, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ctx.assets) != 0 {
+		t.Errorf("expected no assets for links to directories, got=%v", ctx.assets)
+	}
+	if diff := cmp.Diff(input, got); diff != "" {
+		t.Errorf("mismatch in processDocStringWithContext for directories (-want, +got)\n:%s", diff)
+	}
+	if err := copyAssets(ctx.assets, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// docsRsCfg is passed to rustdoc, like docs.rs does, so crates can enable
+// documentation-only features with `#[cfg(docsrs)]`.
+var docsRsCfg = []string{"--cfg", "docsrs"}
+
+// docsRsMetadata is the `[package.metadata.docs.rs]` table in a `Cargo.toml`
+// file.
+//
+// See https://docs.rs/about/metadata for the meaning of each field.
+type docsRsMetadata struct {
+	Features          []string `json:"features"`
+	AllFeatures       bool     `json:"all-features"`
+	NoDefaultFeatures bool     `json:"no-default-features"`
+	RustdocArgs       []string `json:"rustdoc-args"`
+	RustcArgs         []string `json:"rustc-args"`
+}
+
+// readDocsRsMetadata returns the `[package.metadata.docs.rs]` table for `c`,
+// or nil if there is none. Only available with `cargo metadata` discovery.
+func readDocsRsMetadata(c *crate) (*docsRsMetadata, error) {
+	table, ok := c.PackageMetadata["docs"]
+	if !ok {
+		return nil, nil
+	}
+	var docs struct {
+		Rs *docsRsMetadata `json:"rs"`
+	}
+	if err := json.Unmarshal(table, &docs); err != nil {
+		return nil, fmt.Errorf("error parsing [package.metadata.docs.rs] for crate %s: %w", c.Name, err)
+	}
+	return docs.Rs, nil
+}
+
+// buildRustdoc generates the rustdoc JSON files for the crates in `batch`.
+// Returns the crates with a successful build, and the error for each crate
+// that failed to build. The output of the cargo commands is written to `log`.
+//
+// The crates in a batch share a single `cargo doc` invocation. If that fails,
+// e.g. because one of the crates does not compile, it falls back to one
+// `cargo rustdoc` invocation per crate, so a single failure does not skip the
+// whole batch. Some crates always use their own `cargo rustdoc` invocation,
+// see `needsSeparateBuild()`.
+func buildRustdoc(opts *options, batch []*crate, log io.Writer) ([]*crate, map[*crate]error) {
+	var shared, separate []*crate
+	for _, crate := range batch {
+		if needsSeparateBuild(crate) {
+			separate = append(separate, crate)
+		} else {
+			shared = append(shared, crate)
+		}
+	}
+	var built []*crate
+	failed := map[*crate]error{}
+	if len(shared) > 1 {
+		if err := runCmdWithLog(log, opts.ProjectRoot, "cargo", cargoDocArgs(opts.Toolchain, shared)...); err == nil {
+			built = shared
+			shared = nil
+		} else {
+			fmt.Fprintf(log, "Error in cargo doc command, building each crate separately: %v\n", err)
+		}
+	}
+	for _, crate := range slices.Concat(shared, separate) {
+		if err := runCmdWithLog(log, opts.ProjectRoot, "cargo", cargoRustdocArgs(opts.Toolchain, crate)...); err != nil {
+			fmt.Fprintf(log, "Error in cargo rustdoc command: %v\n", err)
+			failed[crate] = fmt.Errorf("error building rustdoc JSON for crate %s: %w", crate.Name, err)
+			continue
+		}
+		built = append(built, crate)
+	}
+	return built, failed
+}
+
+// needsSeparateBuild returns true if the crate cannot share a `cargo doc`
+// invocation with other crates. The rustdoc and rustc flags, and disabling
+// the default features, would apply to all the crates in the invocation.
+func needsSeparateBuild(crate *crate) bool {
+	return len(crate.Config.RustdocArgs) != 0 || len(crate.Config.RustcArgs) != 0 || crate.Config.noDefaultFeatures()
+}
+
+// cargoDocArgs returns the `cargo` arguments to build the rustdoc JSON for
+// multiple crates in a single invocation, using the nightly `toolchain`.
+func cargoDocArgs(toolchain string, crates []*crate) []string {
+	args := []string{"+" + toolchain, "-Z", "unstable-options", "doc", "--output-format=json", "--no-deps"}
+	args = append(args, "--config", tomlArray("build.rustdocflags", docsRsCfg))
+	for _, crate := range crates {
+		args = append(args, "--package", crate.Name)
+		features := crate.Config.Features
+		if crate.Config.allFeatures() {
+			// `--all-features` applies to all the packages, list the
+			// features of this package instead.
+			features = crate.AvailableFeatures
+		}
+		for _, feature := range features {
+			args = append(args, "--features", fmt.Sprintf("%s/%s", crate.Name, feature))
+		}
+	}
+	return args
+}
+
+// cargoRustdocArgs returns the `cargo` arguments to build the rustdoc JSON for
+// a single crate, using the nightly `toolchain`.
+func cargoRustdocArgs(toolchain string, crate *crate) []string {
+	args := []string{"+" + toolchain, "-Z", "unstable-options", "rustdoc", "--output-format=json", "--package", crate.Name}
+	if len(crate.Config.RustcArgs) != 0 {
+		args = append(args, "--config", tomlArray("build.rustflags", crate.Config.RustcArgs))
+	}
+	if crate.Config.allFeatures() {
+		args = append(args, "--all-features")
+	} else if len(crate.Config.Features) != 0 {
+		args = append(args, "--features", strings.Join(crate.Config.Features, ","))
+	}
+	if crate.Config.noDefaultFeatures() {
+		args = append(args, "--no-default-features")
+	}
+	args = append(args, "--")
+	args = append(args, crate.Config.RustdocArgs...)
+	args = append(args, docsRsCfg...)
+	return args
+}
+
+// tomlArray formats a `--config` argument setting `key` to an array of
+// strings.
+func tomlArray(key string, values []string) string {
+	var quoted []string
+	for _, v := range values {
+		quoted = append(quoted, fmt.Sprintf("%q", v))
+	}
+	return fmt.Sprintf("%s=[%s]", key, strings.Join(quoted, ", "))
+}
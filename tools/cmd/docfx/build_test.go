@@ -0,0 +1,211 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestReadDocsRsMetadata(t *testing.T) {
+	input := &crate{
+		Name: "google-cloud-storage",
+		PackageMetadata: map[string]json.RawMessage{
+			"docs": json.RawMessage(`{"rs": {
+				"features": ["a", "b"],
+				"all-features": true,
+				"no-default-features": true,
+				"rustdoc-args": ["--cfg", "google_cloud_unstable"],
+				"rustc-args": ["--cfg", "tokio_unstable"]
+			}}`),
+		},
+	}
+	got, err := readDocsRsMetadata(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &docsRsMetadata{
+		Features:          []string{"a", "b"},
+		AllFeatures:       true,
+		NoDefaultFeatures: true,
+		RustdocArgs:       []string{"--cfg", "google_cloud_unstable"},
+		RustcArgs:         []string{"--cfg", "tokio_unstable"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	for _, metadata := range []map[string]json.RawMessage{
+		nil,
+		{"docs": json.RawMessage(`{"other": {}}`)},
+	} {
+		input.PackageMetadata = metadata
+		if got, err := readDocsRsMetadata(input); err != nil || got != nil {
+			t.Errorf("expected no docs.rs metadata for %v, got=%v, err=%v", metadata, got, err)
+		}
+	}
+
+	input.PackageMetadata = map[string]json.RawMessage{"docs": json.RawMessage(`{"rs": {"features": "a"}}`)}
+	if got, err := readDocsRsMetadata(input); err == nil {
+		t.Errorf("expected an error for invalid docs.rs metadata, got=%v", got)
+	}
+}
+
+func TestCrateConfigDocsRs(t *testing.T) {
+	input := &crate{
+		Name: "google-cloud-storage",
+		PackageMetadata: map[string]json.RawMessage{
+			"docs":  json.RawMessage(`{"rs": {"all-features": true, "rustdoc-args": ["--cfg", "a"], "rustc-args": ["--cfg", "b"]}}`),
+			"docfx": json.RawMessage(`{"rustdoc-args": ["--cfg", "c"]}`),
+		},
+	}
+	cfg := &config{
+		Crates: map[string]crateConfig{
+			"google-cloud-storage": {RustcArgs: []string{"--cfg", "d"}},
+		},
+	}
+	got, err := cfg.crateConfig(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := crateConfig{
+		AllFeatures:       ptr(true),
+		NoDefaultFeatures: ptr(false),
+		RustdocArgs:       []string{"--cfg", "c"},
+		RustcArgs:         []string{"--cfg", "d"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	// The configuration file can disable the docs.rs settings.
+	cfg.Crates["google-cloud-storage"] = crateConfig{AllFeatures: ptr(false)}
+	if got, err = cfg.crateConfig(input); err != nil {
+		t.Fatal(err)
+	}
+	if got.allFeatures() {
+		t.Errorf("expected the configuration file to disable all-features, got=%v", got)
+	}
+	want = crateConfig{
+		AllFeatures:       ptr(false),
+		NoDefaultFeatures: ptr(false),
+		RustdocArgs:       []string{"--cfg", "c"},
+		RustcArgs:         []string{"--cfg", "b"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestCargoRustdocArgs(t *testing.T) {
+	for _, test := range []struct {
+		Config crateConfig
+		Want   []string
+	}{
+		{
+			Config: crateConfig{},
+			Want:   []string{"--", "--cfg", "docsrs"},
+		},
+		{
+			Config: crateConfig{
+				Features:    []string{"a", "b"},
+				RustdocArgs: []string{"--cfg", "test"},
+			},
+			Want: []string{"--features", "a,b", "--", "--cfg", "test", "--cfg", "docsrs"},
+		},
+		{
+			Config: crateConfig{
+				Features:          []string{"a"},
+				AllFeatures:       ptr(true),
+				NoDefaultFeatures: ptr(true),
+				RustcArgs:         []string{"--cfg", "tokio_unstable"},
+			},
+			Want: []string{`--config`, `build.rustflags=["--cfg", "tokio_unstable"]`, "--all-features", "--no-default-features", "--", "--cfg", "docsrs"},
+		},
+	} {
+		input := &crate{Name: "google-cloud-storage", Config: test.Config}
+		got := cargoRustdocArgs("nightly", input)
+		want := append([]string{"+nightly", "-Z", "unstable-options", "rustdoc", "--output-format=json", "--package", "google-cloud-storage"}, test.Want...)
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch for %+v (-want, +got):\n%s", test.Config, diff)
+		}
+	}
+}
+
+func TestCargoDocArgs(t *testing.T) {
+	input := []*crate{
+		{Name: "google-cloud-wkt"},
+		{Name: "google-cloud-storage", Config: crateConfig{Features: []string{"a"}}},
+		{Name: "google-cloud-pubsub", Config: crateConfig{AllFeatures: ptr(true)}, AvailableFeatures: []string{"default", "unstable"}},
+	}
+	got := cargoDocArgs("nightly-2025-08-01", input)
+	want := []string{
+		"+nightly-2025-08-01", "-Z", "unstable-options", "doc", "--output-format=json", "--no-deps",
+		"--config", `build.rustdocflags=["--cfg", "docsrs"]`,
+		"--package", "google-cloud-wkt",
+		"--package", "google-cloud-storage", "--features", "google-cloud-storage/a",
+		"--package", "google-cloud-pubsub", "--features", "google-cloud-pubsub/default", "--features", "google-cloud-pubsub/unstable",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestNeedsSeparateBuild(t *testing.T) {
+	for _, test := range []struct {
+		Config crateConfig
+		Want   bool
+	}{
+		{crateConfig{}, false},
+		{crateConfig{Features: []string{"a"}, AllFeatures: ptr(true)}, false},
+		{crateConfig{NoDefaultFeatures: ptr(true)}, true},
+		{crateConfig{RustdocArgs: []string{"--cfg", "a"}}, true},
+		{crateConfig{RustcArgs: []string{"--cfg", "a"}}, true},
+	} {
+		if got := needsSeparateBuild(&crate{Config: test.Config}); got != test.Want {
+			t.Errorf("mismatch for %+v, want=%v, got=%v", test.Config, test.Want, got)
+		}
+	}
+}
+
+func TestBuildRustdocFailures(t *testing.T) {
+	// A fake `cargo` that fails for any command involving the `bad` crate.
+	bin := t.TempDir()
+	script := "#!/bin/sh\ncase \"$*\" in *bad*) exit 1;; esac\nexit 0\n"
+	if err := os.WriteFile(filepath.Join(bin, "cargo"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	good, bad, other := &crate{Name: "good"}, &crate{Name: "bad"}, &crate{Name: "other"}
+	var log bytes.Buffer
+	built, failed := buildRustdoc(&options{Toolchain: "nightly"}, []*crate{good, bad, other}, &log)
+	if diff := cmp.Diff([]*crate{good, other}, built, cmpopts.IgnoreUnexported(crate{})); diff != "" {
+		t.Errorf("mismatch in built crates (-want, +got):\n%s", diff)
+	}
+	if len(failed) != 1 || failed[bad] == nil || !strings.Contains(failed[bad].Error(), "crate bad") {
+		t.Errorf("expected a single failure for the bad crate, got=%v", failed)
+	}
+	if !strings.Contains(log.String(), "Error in cargo rustdoc command") {
+		t.Errorf("expected the build errors in the log, got=%s", log.String())
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// generatorVersion identifies the version of the generator code in the cache
+// manifest. Change it when the generated files change for the same input, so
+// the next run regenerates all the crates. Changes to the templates are
+// detected automatically.
+const generatorVersion = "1"
+
+// defaultCacheFile is the name of the cache manifest in the output directory.
+const defaultCacheFile = ".rustdocfx-cache.json"
+
+// cacheManifest records the inputs used to generate and upload each crate.
+// Crates with the same inputs as the last successful run are skipped.
+//
+// The manifest is a JSON file. CI builds can save and restore it across runs.
+type cacheManifest struct {
+	Crates map[string]cacheEntry `json:"crates"`
+
+	mu sync.Mutex
+}
+
+// cacheEntry contains the inputs for a single crate.
+type cacheEntry struct {
+	Version string `json:"version"`
+	// RustdocHash is the hash of the rustdoc JSON file.
+	RustdocHash string `json:"rustdoc_hash"`
+	// FilesHash is the hash of the other files used to generate the crate,
+	// such as the `.repo-metadata.json` and `README.md` files, and the
+	// generated serializer.
+	FilesHash string `json:"files_hash"`
+	// Generator is the hash of the generator version, its templates, the
+	// crate overrides, the options that change the output, and the staging
+	// bucket.
+	Generator string `json:"generator"`
+	// Assets maps the local files copied into the output, relative to the
+	// crate directory, to the hash of their contents. The files are only
+	// known after generating the crate, each run checks the files recorded
+	// by the previous run.
+	Assets map[string]string `json:"assets,omitempty"`
+}
+
+// equal returns true if both entries have the same inputs.
+func (e cacheEntry) equal(o cacheEntry) bool {
+	return e.Version == o.Version &&
+		e.RustdocHash == o.RustdocHash &&
+		e.FilesHash == o.FilesHash &&
+		e.Generator == o.Generator &&
+		maps.Equal(e.Assets, o.Assets)
+}
+
+// loadCache reads the cache manifest in `path`. Returns an empty manifest if
+// the file does not exist.
+func loadCache(path string) (*cacheManifest, error) {
+	m := &cacheManifest{Crates: map[string]cacheEntry{}}
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(contents, m); err != nil {
+		return nil, fmt.Errorf("error parsing cache manifest %s: %w", path, err)
+	}
+	if m.Crates == nil {
+		m.Crates = map[string]cacheEntry{}
+	}
+	return m, nil
+}
+
+// unchanged returns true if `name` was generated with the same inputs. The
+// assets recorded in the manifest are read from `location`, `entry` does not
+// include them.
+func (m *cacheManifest) unchanged(name string, entry cacheEntry, location string) bool {
+	m.mu.Lock()
+	got, ok := m.Crates[name]
+	m.mu.Unlock()
+	if !ok {
+		return false
+	}
+	assets, err := hashAssets(location, slices.Collect(maps.Keys(got.Assets)))
+	if err != nil {
+		// The asset is gone or unreadable, generate the crate again to
+		// report any errors.
+		return false
+	}
+	entry.Assets = assets
+	return got.equal(entry)
+}
+
+// record saves the inputs for a successfully generated crate.
+func (m *cacheManifest) record(name string, entry cacheEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Crates[name] = entry
+}
+
+// save writes the manifest to `path`.
+func (m *cacheManifest) save(path string) error {
+	m.mu.Lock()
+	contents, err := json.MarshalIndent(m, "", "  ")
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(contents, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// newCacheEntry computes the cache entry for `crate`, with the contents of
+// its rustdoc JSON file in `rustdoc`.
+func newCacheEntry(opts *options, crate *crate, rustdoc []byte) (cacheEntry, error) {
+	generator, err := templatesHash()
+	if err != nil {
+		return cacheEntry{}, err
+	}
+	h := sha256.New()
+	h.Write([]byte(generator))
+	h.Write([]byte(strconv.Itoa(opts.HeadingBase)))
+	// A crate generated without uploading it, or uploaded to a different
+	// bucket, must be uploaded in the next run.
+	fmt.Fprintf(h, "\nupload=%s\n", opts.Upload)
+	config, err := json.Marshal(crate.Config)
+	if err != nil {
+		return cacheEntry{}, err
+	}
+	h.Write(config)
+
+	files := sha256.New()
+	for _, name := range []string{".repo-metadata.json", "README.md", generatedModelFile} {
+		contents, err := os.ReadFile(filepath.Join(crate.Location, filepath.FromSlash(name)))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return cacheEntry{}, err
+		}
+		fmt.Fprintf(files, "%s %d\n", name, len(contents))
+		files.Write(contents)
+	}
+
+	return cacheEntry{
+		Version:     crate.Version,
+		RustdocHash: hashBytes(rustdoc),
+		FilesHash:   hex.EncodeToString(files.Sum(nil)),
+		Generator:   hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+// hashAssets returns the hash of each file in `paths`, relative to `location`.
+func hashAssets(location string, paths []string) (map[string]string, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	hashes := map[string]string{}
+	for _, path := range paths {
+		contents, err := os.ReadFile(filepath.Join(location, filepath.FromSlash(path)))
+		if err != nil {
+			return nil, err
+		}
+		hashes[path] = hashBytes(contents)
+	}
+	return hashes, nil
+}
+
+// assetSources records the local files copied into the output of a crate.
+type assetSources struct {
+	mu    sync.Mutex
+	paths map[string]bool
+}
+
+func (a *assetSources) add(path string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.paths == nil {
+		a.paths = map[string]bool{}
+	}
+	a.paths[path] = true
+}
+
+func (a *assetSources) sorted() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return slices.Sorted(maps.Keys(a.paths))
+}
+
+// templatesHash returns a hash of the generator version and all the embedded
+// templates.
+var templatesHash = sync.OnceValues(func() (string, error) {
+	h := sha256.New()
+	h.Write([]byte(generatorVersion))
+	err := fs.WalkDir(templates, "templates", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		contents, err := templates.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s %d\n", path, len(contents))
+		h.Write(contents)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+})
+
+func hashBytes(contents []byte) string {
+	sum := sha256.Sum256(contents)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,146 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestCacheRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", defaultCacheFile)
+	cache, err := loadCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := cacheEntry{Version: "1.2.3", RustdocHash: "r", FilesHash: "f", Generator: "g"}
+	if cache.unchanged("google-cloud-wkt", entry, "") {
+		t.Errorf("expected a new crate to be changed")
+	}
+	cache.record("google-cloud-wkt", entry)
+	if err := cache.save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := loadCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &cacheManifest{Crates: map[string]cacheEntry{"google-cloud-wkt": entry}}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreUnexported(cacheManifest{})); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	if !got.unchanged("google-cloud-wkt", entry, "") {
+		t.Errorf("expected an unchanged crate")
+	}
+	changed := entry
+	changed.RustdocHash = "changed"
+	if got.unchanged("google-cloud-wkt", changed, "") {
+		t.Errorf("expected a changed crate with a different rustdoc hash")
+	}
+}
+
+func TestCacheAssets(t *testing.T) {
+	location := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(location, "images"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	logo := filepath.Join(location, "images", "logo.png")
+	if err := os.WriteFile(logo, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cache, err := loadCache(filepath.Join(t.TempDir(), defaultCacheFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := cacheEntry{Version: "1.2.3", RustdocHash: "r", FilesHash: "f", Generator: "g"}
+	recorded := entry
+	if recorded.Assets, err = hashAssets(location, []string{"images/logo.png"}); err != nil {
+		t.Fatal(err)
+	}
+	cache.record("google-cloud-wkt", recorded)
+	if !cache.unchanged("google-cloud-wkt", entry, location) {
+		t.Errorf("expected an unchanged crate")
+	}
+
+	if err := os.WriteFile(logo, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if cache.unchanged("google-cloud-wkt", entry, location) {
+		t.Errorf("expected a changed crate after changing an asset")
+	}
+	if err := os.Remove(logo); err != nil {
+		t.Fatal(err)
+	}
+	if cache.unchanged("google-cloud-wkt", entry, location) {
+		t.Errorf("expected a changed crate after removing an asset")
+	}
+}
+
+func TestLoadCacheBadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), defaultCacheFile)
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := loadCache(path); err == nil {
+		t.Errorf("expected an error, got=%v", got)
+	}
+}
+
+func TestNewCacheEntry(t *testing.T) {
+	location := t.TempDir()
+	input := &crate{Name: "test-only", Version: "1.2.3", Location: location}
+	opts := &options{HeadingBase: defaultHeadingBase}
+	rustdoc := []byte(`{"root": 0}`)
+
+	base, err := newCacheEntry(opts, input, rustdoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := newCacheEntry(opts, input, rustdoc); err != nil || !got.equal(base) {
+		t.Errorf("expected a stable cache entry, got=%v, want=%v, err=%v", got, base, err)
+	}
+
+	got, err := newCacheEntry(opts, input, []byte(`{"root": 1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.RustdocHash == base.RustdocHash {
+		t.Errorf("expected a different rustdoc hash for a different input")
+	}
+
+	got, err = newCacheEntry(&options{HeadingBase: 2}, input, rustdoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Generator == base.Generator {
+		t.Errorf("expected a different generator hash with a different heading base")
+	}
+
+	if err := os.WriteFile(filepath.Join(location, "README.md"), []byte("# Test"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err = newCacheEntry(opts, input, rustdoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.FilesHash == base.FilesHash {
+		t.Errorf("expected a different files hash after adding a README")
+	}
+}
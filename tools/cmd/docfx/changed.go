@@ -0,0 +1,87 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// changedFiles returns the absolute paths of the files in `opts.ProjectRoot`
+// that changed since the `opts.Since` git reference.
+func changedFiles(opts *options) ([]string, error) {
+	root, err := filepath.Abs(opts.ProjectRoot)
+	if err != nil {
+		return nil, err
+	}
+	var stdout bytes.Buffer
+	if err := runCmd(&stdout, root, "git", "diff", "--name-only", "--relative", opts.Since, "--"); err != nil {
+		return nil, fmt.Errorf("error listing files changed since %s: %w", opts.Since, err)
+	}
+	var files []string
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, filepath.Join(root, filepath.FromSlash(line)))
+		}
+	}
+	return files, nil
+}
+
+// changedCrates returns the names of the crates containing any of `files`.
+// A file belongs to the crate with the longest `Location` containing it, as
+// crates may be nested in the directory of other crates.
+//
+// With `reverseDependencies`, the result also includes all the crates that
+// depend, directly or indirectly, on a changed crate.
+func changedCrates(crates []crate, files []string, reverseDependencies bool) ([]string, error) {
+	var changed []string
+	for _, file := range files {
+		owner := ""
+		longest := -1
+		for _, c := range crates {
+			location, err := filepath.Abs(c.Location)
+			if err != nil {
+				return nil, err
+			}
+			if (file == location || strings.HasPrefix(file, location+string(filepath.Separator))) && len(location) > longest {
+				owner, longest = c.Name, len(location)
+			}
+		}
+		if owner != "" && !slices.Contains(changed, owner) {
+			changed = append(changed, owner)
+		}
+	}
+	if !reverseDependencies {
+		return changed, nil
+	}
+	// Iterate until no new crates are added, this handles indirect
+	// dependencies.
+	for added := true; added; {
+		added = false
+		for _, c := range crates {
+			if slices.Contains(changed, c.Name) {
+				continue
+			}
+			if slices.ContainsFunc(c.Dependencies, func(d string) bool { return slices.Contains(changed, d) }) {
+				changed = append(changed, c.Name)
+				added = true
+			}
+		}
+	}
+	return changed, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestChangedCrates(t *testing.T) {
+	crates := []crate{
+		{Name: "google-cloud-wkt", Location: "/project/src/wkt"},
+		{Name: "google-cloud-gax", Location: "/project/src/gax", Dependencies: []string{"google-cloud-wkt"}},
+		{Name: "google-cloud-storage", Location: "/project/src/storage", Dependencies: []string{"google-cloud-gax", "google-cloud-wkt"}},
+		{Name: "google-cloud-storage-nested", Location: "/project/src/storage/nested"},
+		{Name: "google-cloud-pubsub", Location: "/project/src/pubsub"},
+	}
+	for _, test := range []struct {
+		Files               []string
+		ReverseDependencies bool
+		Want                []string
+	}{
+		{[]string{"/project/src/storage/src/lib.rs"}, false, []string{"google-cloud-storage"}},
+		{[]string{"/project/src/storage/nested/src/lib.rs"}, false, []string{"google-cloud-storage-nested"}},
+		{[]string{"/project/src/storage-other/lib.rs", "/project/README.md"}, false, nil},
+		{[]string{"/project/src/wkt/src/lib.rs", "/project/src/wkt/Cargo.toml"}, false, []string{"google-cloud-wkt"}},
+		{[]string{"/project/src/wkt/src/lib.rs"}, true, []string{"google-cloud-wkt", "google-cloud-gax", "google-cloud-storage"}},
+		{[]string{"/project/src/gax/src/lib.rs"}, true, []string{"google-cloud-gax", "google-cloud-storage"}},
+		{[]string{"/project/src/pubsub/src/lib.rs"}, true, []string{"google-cloud-pubsub"}},
+	} {
+		got, err := changedCrates(crates, test.Files, test.ReverseDependencies)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(test.Want, got); diff != "" {
+			t.Errorf("mismatch for %v, reverseDependencies=%v (-want, +got):\n%s", test.Files, test.ReverseDependencies, diff)
+		}
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	root := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.email=test@example.com", "-c", "user.name=test"}, args...)...)
+		cmd.Dir = root
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	write := func(name, contents string) {
+		t.Helper()
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("src/wkt/src/lib.rs", "// wkt")
+	write("src/storage/src/lib.rs", "// storage")
+	git("init", "--quiet")
+	git("add", "-A")
+	git("commit", "--quiet", "-m", "initial")
+	write("src/storage/src/lib.rs", "// storage v2")
+
+	got, err := changedFiles(&options{ProjectRoot: root, Since: "HEAD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(abs, "src", "storage", "src", "lib.rs")}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	fspath "path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFile is the name of the configuration file in the project root.
+const defaultConfigFile = "rustdocfx.yaml"
+
+// The values for `crateConfig.TocGrouping`.
+const (
+	// tocGroupingGapic groups the TOC by clients, request builders, model,
+	// and errors, see `regroupGapicTOC()`.
+	tocGroupingGapic = "gapic"
+	// tocGroupingModules groups the TOC by modules and item kinds.
+	tocGroupingModules = "modules"
+)
+
+// config is the contents of the `rustdocfx.yaml` configuration file.
+//
+// For example:
+//
+//	include:
+//	  - google-cloud-*
+//	exclude:
+//	  - re:.*-internal$
+//	crates:
+//	  google-cloud-storage:
+//	    features: [unstable-stream]
+//	    rustdoc-args: [--cfg, google_cloud_unstable_tracing]
+//	    toc-grouping: modules
+//	  google-cloud-test-utils:
+//	    skip: only used in tests
+type config struct {
+	// Include selects the crates to generate, all the crates if empty. Each
+	// entry is a glob pattern, or a regular expression with a `re:` prefix.
+	Include []string `yaml:"include"`
+	// Exclude removes crates from the selection, with the same syntax as
+	// Include.
+	Exclude []string `yaml:"exclude"`
+	// Crates contains the overrides for each crate, indexed by crate name.
+	Crates map[string]crateConfig `yaml:"crates"`
+}
+
+// crateConfig contains the overrides for a single crate. They can be set in
+// the configuration file, or in the `[package.metadata.docfx]` table in the
+// crate's `Cargo.toml` file. The configuration file takes precedence.
+//
+// The defaults for the build options come from the
+// `[package.metadata.docs.rs]` table, so the generated documentation matches
+// docs.rs.
+type crateConfig struct {
+	// Features to enable when building the rustdoc JSON.
+	Features []string `yaml:"features" json:"features"`
+	// AllFeatures enables all the features when building the rustdoc JSON.
+	// A pointer, so an override can disable the docs.rs setting.
+	AllFeatures *bool `yaml:"all-features" json:"all-features"`
+	// NoDefaultFeatures disables the default features. A pointer, so an
+	// override can disable the docs.rs setting.
+	NoDefaultFeatures *bool `yaml:"no-default-features" json:"no-default-features"`
+	// RustdocArgs are additional flags for rustdoc.
+	RustdocArgs []string `yaml:"rustdoc-args" json:"rustdoc-args"`
+	// RustcArgs are additional flags for rustc.
+	RustcArgs []string `yaml:"rustc-args" json:"rustc-args"`
+	// TocGrouping overrides how the table of contents is grouped, one of
+	// `tocGroupingGapic` or `tocGroupingModules`.
+	TocGrouping string `yaml:"toc-grouping" json:"toc-grouping"`
+	// Skip, if not empty, is the reason to skip the crate.
+	Skip string `yaml:"skip" json:"skip"`
+}
+
+// defaultConfig contains the built-in configuration. Any configuration file
+// is merged over it, see `loadConfig()`.
+func defaultConfig() *config {
+	return &config{
+		// These crates do not get documents at cloud.google.com.
+		Exclude: []string{"google-cloud-gax-internal"},
+	}
+}
+
+// loadConfig reads the configuration file in `filename`. If `filename` is
+// empty it reads the `defaultConfigFile` in `projectRoot`, using the
+// `defaultConfig()` if that file does not exist.
+//
+// The exclusions in `defaultConfig()` always apply, the configuration file
+// can only add more exclusions.
+func loadConfig(filename, projectRoot string) (*config, error) {
+	explicit := filename != ""
+	if !explicit {
+		filename = filepath.Join(projectRoot, defaultConfigFile)
+	}
+	contents, err := os.ReadFile(filename)
+	if !explicit && errors.Is(err, fs.ErrNotExist) {
+		return defaultConfig(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := parseConfig(contents)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Exclude = slices.Concat(defaultConfig().Exclude, cfg.Exclude)
+	return cfg, nil
+}
+
+func parseConfig(contents []byte) (*config, error) {
+	cfg := &config{}
+	if err := yaml.Unmarshal(contents, cfg); err != nil {
+		return nil, fmt.Errorf("error parsing configuration file: %w", err)
+	}
+	for _, p := range slices.Concat(cfg.Include, cfg.Exclude) {
+		if _, err := matchPattern(p, ""); err != nil {
+			return nil, err
+		}
+	}
+	for name, c := range cfg.Crates {
+		if err := c.validate(); err != nil {
+			return nil, fmt.Errorf("invalid configuration for crate %s: %w", name, err)
+		}
+	}
+	return cfg, nil
+}
+
+func (c *crateConfig) validate() error {
+	switch c.TocGrouping {
+	case "", tocGroupingGapic, tocGroupingModules:
+		return nil
+	}
+	return fmt.Errorf("invalid toc-grouping %q, must be %q or %q", c.TocGrouping, tocGroupingGapic, tocGroupingModules)
+}
+
+// selected returns true if the include and exclude patterns select the crate.
+func (cfg *config) selected(name string) (bool, error) {
+	if len(cfg.Include) != 0 {
+		included, err := matchAny(cfg.Include, name)
+		if err != nil || !included {
+			return false, err
+		}
+	}
+	excluded, err := matchAny(cfg.Exclude, name)
+	return !excluded, err
+}
+
+// crateConfig returns the overrides for `c`, merging the
+// `[package.metadata.docs.rs]` table, the `[package.metadata.docfx]` table and
+// the configuration file.
+func (cfg *config) crateConfig(c *crate) (crateConfig, error) {
+	var result crateConfig
+	docsRs, err := readDocsRsMetadata(c)
+	if err != nil {
+		return result, err
+	}
+	if docsRs != nil {
+		result = crateConfig{
+			Features:          docsRs.Features,
+			AllFeatures:       &docsRs.AllFeatures,
+			NoDefaultFeatures: &docsRs.NoDefaultFeatures,
+			RustdocArgs:       docsRs.RustdocArgs,
+			RustcArgs:         docsRs.RustcArgs,
+		}
+	}
+	if table, ok := c.PackageMetadata["docfx"]; ok {
+		var docfx crateConfig
+		if err := json.Unmarshal(table, &docfx); err != nil {
+			return result, fmt.Errorf("error parsing [package.metadata.docfx] for crate %s: %w", c.Name, err)
+		}
+		if err := docfx.validate(); err != nil {
+			return result, fmt.Errorf("invalid [package.metadata.docfx] for crate %s: %w", c.Name, err)
+		}
+		result.merge(docfx)
+	}
+	if override, ok := cfg.Crates[c.Name]; ok {
+		result.merge(override)
+	}
+	return result, nil
+}
+
+// merge replaces the fields in `c` with the fields set in `override`.
+func (c *crateConfig) merge(override crateConfig) {
+	if override.Features != nil {
+		c.Features = override.Features
+	}
+	if override.AllFeatures != nil {
+		c.AllFeatures = override.AllFeatures
+	}
+	if override.NoDefaultFeatures != nil {
+		c.NoDefaultFeatures = override.NoDefaultFeatures
+	}
+	if override.RustdocArgs != nil {
+		c.RustdocArgs = override.RustdocArgs
+	}
+	if override.RustcArgs != nil {
+		c.RustcArgs = override.RustcArgs
+	}
+	if override.TocGrouping != "" {
+		c.TocGrouping = override.TocGrouping
+	}
+	if override.Skip != "" {
+		c.Skip = override.Skip
+	}
+}
+
+// allFeatures returns true if the rustdoc JSON is built with all the features.
+func (c *crateConfig) allFeatures() bool {
+	return c.AllFeatures != nil && *c.AllFeatures
+}
+
+// noDefaultFeatures returns true if the rustdoc JSON is built without the
+// default features.
+func (c *crateConfig) noDefaultFeatures() bool {
+	return c.NoDefaultFeatures != nil && *c.NoDefaultFeatures
+}
+
+func matchAny(patterns []string, name string) (bool, error) {
+	for _, p := range patterns {
+		ok, err := matchPattern(p, name)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// matchPattern returns true if `name` matches `pattern`, a glob pattern or a
+// regular expression with a `re:` prefix. Regular expressions must match the
+// full name.
+func matchPattern(pattern, name string) (bool, error) {
+	if expr, ok := strings.CutPrefix(pattern, "re:"); ok {
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return false, fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+		}
+		return re.MatchString(name), nil
+	}
+	ok, err := fspath.Match(pattern, name)
+	if err != nil {
+		return false, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+	}
+	return ok, nil
+}
@@ -0,0 +1,198 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseConfig(t *testing.T) {
+	input := `
+include:
+  - google-cloud-*
+exclude:
+  - re:.*-internal
+crates:
+  google-cloud-storage:
+    features: [unstable-stream]
+    rustdoc-args: [--cfg, google_cloud_unstable_tracing]
+    toc-grouping: modules
+  google-cloud-test-utils:
+    skip: only used in tests
+`
+	got, err := parseConfig([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &config{
+		Include: []string{"google-cloud-*"},
+		Exclude: []string{"re:.*-internal"},
+		Crates: map[string]crateConfig{
+			"google-cloud-storage": {
+				Features:    []string{"unstable-stream"},
+				RustdocArgs: []string{"--cfg", "google_cloud_unstable_tracing"},
+				TocGrouping: tocGroupingModules,
+			},
+			"google-cloud-test-utils": {Skip: "only used in tests"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	for _, input := range []string{
+		"include: [\"re:(\"]",
+		"exclude: [\"[\"]",
+		"crates:\n  google-cloud-storage:\n    toc-grouping: flat",
+		"include: not-a-list-of-patterns: true",
+	} {
+		if got, err := parseConfig([]byte(input)); err == nil {
+			t.Errorf("expected an error for %q, got=%v", input, got)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	projectRoot := t.TempDir()
+	got, err := loadConfig("", projectRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(defaultConfig(), got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	if _, err := loadConfig(filepath.Join(projectRoot, "missing.yaml"), projectRoot); err == nil {
+		t.Errorf("expected an error for a missing explicit configuration file")
+	}
+
+	if err := os.WriteFile(filepath.Join(projectRoot, defaultConfigFile), []byte("exclude: [google-cloud-wkt]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err = loadConfig("", projectRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The built-in exclusions always apply.
+	want := &config{Exclude: []string{"google-cloud-gax-internal", "google-cloud-wkt"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	if ok, err := got.selected("google-cloud-gax-internal"); err != nil || ok {
+		t.Errorf("expected google-cloud-gax-internal to be excluded with a configuration file, got=%v, err=%v", ok, err)
+	}
+}
+
+func TestConfigSelected(t *testing.T) {
+	cfg := &config{
+		Include: []string{"google-cloud-*", "re:gcp-sdk-(a|b)"},
+		Exclude: []string{"*-internal", "re:google-cloud-.*-v[0-9]+beta"},
+	}
+	for _, test := range []struct {
+		Name string
+		Want bool
+	}{
+		{"google-cloud-storage", true},
+		{"google-cloud-gax-internal", false},
+		{"google-cloud-secretmanager-v1", true},
+		{"google-cloud-secretmanager-v1beta", false},
+		{"gcp-sdk-a", true},
+		{"gcp-sdk-ab", false},
+		{"other", false},
+	} {
+		got, err := cfg.selected(test.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.Want {
+			t.Errorf("mismatch for %s, want=%v, got=%v", test.Name, test.Want, got)
+		}
+	}
+
+	// Without include patterns all the crates are selected, except the
+	// default exclusions.
+	got, err := defaultConfig().selected("google-cloud-gax-internal")
+	if err != nil || got {
+		t.Errorf("expected google-cloud-gax-internal to be excluded by default, got=%v, err=%v", got, err)
+	}
+	got, err = defaultConfig().selected("google-cloud-storage")
+	if err != nil || !got {
+		t.Errorf("expected google-cloud-storage to be included by default, got=%v, err=%v", got, err)
+	}
+}
+
+func TestCrateConfig(t *testing.T) {
+	cfg := &config{
+		Crates: map[string]crateConfig{
+			"google-cloud-storage": {
+				Features:    []string{"from-config"},
+				TocGrouping: tocGroupingGapic,
+			},
+		},
+	}
+	input := &crate{
+		Name: "google-cloud-storage",
+		PackageMetadata: map[string]json.RawMessage{
+			"docfx": json.RawMessage(`{"features": ["from-cargo"], "rustdoc-args": ["--cfg", "test"], "toc-grouping": "modules"}`),
+		},
+	}
+	got, err := cfg.crateConfig(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := crateConfig{
+		Features:    []string{"from-config"},
+		RustdocArgs: []string{"--cfg", "test"},
+		TocGrouping: tocGroupingGapic,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	input.PackageMetadata["docfx"] = json.RawMessage(`{"toc-grouping": "flat"}`)
+	if got, err := (&config{}).crateConfig(input); err == nil {
+		t.Errorf("expected an error with an invalid toc-grouping, got=%v", got)
+	}
+}
+
+func TestCrateConfigFeatureOverrides(t *testing.T) {
+	for _, test := range []struct {
+		Base     crateConfig
+		Override crateConfig
+		Want     bool
+	}{
+		{crateConfig{}, crateConfig{}, false},
+		{crateConfig{AllFeatures: ptr(true)}, crateConfig{}, true},
+		{crateConfig{AllFeatures: ptr(true)}, crateConfig{AllFeatures: ptr(false)}, false},
+		{crateConfig{AllFeatures: ptr(false)}, crateConfig{AllFeatures: ptr(true)}, true},
+	} {
+		got := test.Base
+		got.merge(test.Override)
+		if got.allFeatures() != test.Want {
+			t.Errorf("mismatch merging %v over %v, want=%v, got=%v", test.Override, test.Base, test.Want, got.allFeatures())
+		}
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
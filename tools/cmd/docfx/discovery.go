@@ -0,0 +1,147 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// The commands to discover the crates in the workspace.
+const (
+	// discoveryCargoMetadata uses `cargo metadata`, which requires no
+	// additional tools.
+	discoveryCargoMetadata = "cargo-metadata"
+	// discoveryCargoWorkspaces uses `cargo workspaces plan --json`, from the
+	// `cargo-workspaces` plugin.
+	discoveryCargoWorkspaces = "cargo-workspaces"
+)
+
+// loadWorkspace returns the crates in the workspace, reading them from
+// `opts.Workspace` or running the discovery command.
+func loadWorkspace(opts *options) ([]crate, error) {
+	var contents []byte
+	if opts.Workspace != "" {
+		var err error
+		if contents, err = os.ReadFile(opts.Workspace); err != nil {
+			return nil, err
+		}
+	} else {
+		args := []string{"metadata", "--format-version", "1", "--no-deps"}
+		if opts.Discovery == discoveryCargoWorkspaces {
+			args = []string{"workspaces", "plan", "--json"}
+		}
+		var stdout bytes.Buffer
+		if err := runCmd(&stdout, opts.ProjectRoot, "cargo", args...); err != nil {
+			return nil, fmt.Errorf("unable to get package list: %w", err)
+		}
+		fmt.Printf("using cargo %s for crates\n", args[0])
+		contents = stdout.Bytes()
+	}
+	if opts.ReverseDependencies && isWorkspacesPlan(contents) {
+		// The plan does not include the dependencies of each crate.
+		return nil, fmt.Errorf("-reverse-deps requires the output of `cargo metadata` in -workspace")
+	}
+	crates, err := parseWorkspace(contents)
+	if err != nil {
+		return nil, err
+	}
+	for i := range crates {
+		if crates[i].Location != "" && !filepath.IsAbs(crates[i].Location) {
+			crates[i].Location = filepath.Join(opts.ProjectRoot, crates[i].Location)
+		}
+	}
+	return crates, nil
+}
+
+// parseWorkspace parses the list of crates in a workspace. The input is
+// either the output of `cargo workspaces plan --json`, a JSON array, or the
+// output of `cargo metadata --format-version 1`, a JSON object.
+func parseWorkspace(contents []byte) ([]crate, error) {
+	if isWorkspacesPlan(contents) {
+		return getWorkspaceCrates(contents)
+	}
+	return parseCargoMetadata(contents)
+}
+
+// isWorkspacesPlan returns true if `contents` is the output of `cargo
+// workspaces plan --json`.
+func isWorkspacesPlan(contents []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(contents), []byte("["))
+}
+
+// cargoMetadata simplifies parsing the output of `cargo metadata`.
+type cargoMetadata struct {
+	Packages         []cargoPackage `json:"packages"`
+	WorkspaceMembers []string       `json:"workspace_members"`
+}
+
+type cargoPackage struct {
+	Id           string `json:"id"`
+	Name         string `json:"name"`
+	Version      string `json:"version"`
+	ManifestPath string `json:"manifest_path"`
+	// Publish is `null` if the package can be published to any registry,
+	// and empty with `publish = false`.
+	Publish      *[]string                  `json:"publish"`
+	Metadata     map[string]json.RawMessage `json:"metadata"`
+	Dependencies []cargoDependency          `json:"dependencies"`
+	Features     map[string][]string        `json:"features"`
+}
+
+type cargoDependency struct {
+	Name string `json:"name"`
+	// Kind is `null` for normal dependencies, "dev" or "build" otherwise.
+	Kind *string `json:"kind"`
+}
+
+// parseCargoMetadata returns the workspace members in the output of `cargo
+// metadata`. Like `cargo workspaces plan`, it skips the packages with
+// `publish = false`.
+func parseCargoMetadata(contents []byte) ([]crate, error) {
+	var metadata cargoMetadata
+	if err := json.Unmarshal(contents, &metadata); err != nil {
+		return nil, fmt.Errorf("cargo metadata unmarshal error: %w", err)
+	}
+	var crates []crate
+	for _, p := range metadata.Packages {
+		if !slices.Contains(metadata.WorkspaceMembers, p.Id) {
+			continue
+		}
+		if p.Publish != nil && len(*p.Publish) == 0 {
+			continue
+		}
+		var dependencies []string
+		for _, d := range p.Dependencies {
+			if d.Kind == nil {
+				dependencies = append(dependencies, d.Name)
+			}
+		}
+		crates = append(crates, crate{
+			Name:              p.Name,
+			Version:           p.Version,
+			Location:          filepath.Dir(p.ManifestPath),
+			PackageMetadata:   p.Metadata,
+			Dependencies:      dependencies,
+			AvailableFeatures: slices.Sorted(maps.Keys(p.Features)),
+		})
+	}
+	return crates, nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestParseWorkspacePlan(t *testing.T) {
+	input := `[
+		{"name": "google-cloud-wkt", "version": "1.0.0", "location": "/src/wkt", "private": false},
+		{"name": "google-cloud-storage", "version": "1.2.3", "location": "/src/storage", "private": false}
+	]`
+	got, err := parseWorkspace([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []crate{
+		{Name: "google-cloud-wkt", Version: "1.0.0", Location: "/src/wkt"},
+		{Name: "google-cloud-storage", Version: "1.2.3", Location: "/src/storage"},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreUnexported(crate{})); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestParseWorkspaceCargoMetadata(t *testing.T) {
+	input := `{
+		"packages": [
+			{"id": "wkt-id", "name": "google-cloud-wkt", "version": "1.0.0", "manifest_path": "/src/wkt/Cargo.toml"},
+			{"id": "serde-id", "name": "serde", "version": "1.0.219", "manifest_path": "/registry/serde/Cargo.toml"},
+			{"id": "storage-id", "name": "google-cloud-storage", "version": "1.2.3", "manifest_path": "/src/storage/Cargo.toml"}
+		],
+		"workspace_members": ["wkt-id", "storage-id"]
+	}`
+	got, err := parseWorkspace([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []crate{
+		{Name: "google-cloud-wkt", Version: "1.0.0", Location: "/src/wkt"},
+		{Name: "google-cloud-storage", Version: "1.2.3", Location: "/src/storage"},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreUnexported(crate{})); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestLoadWorkspaceRelativeLocation(t *testing.T) {
+	dir := t.TempDir()
+	workspaceFile := filepath.Join(dir, "plan.json")
+	if err := os.WriteFile(workspaceFile, []byte(`[{"name": "google-cloud-wkt", "version": "1.0.0", "location": "src/wkt"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadWorkspace(&options{ProjectRoot: "/project", Workspace: workspaceFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []crate{{Name: "google-cloud-wkt", Version: "1.0.0", Location: "/project/src/wkt"}}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreUnexported(crate{})); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestLoadWorkspaceReverseDependenciesRequireMetadata(t *testing.T) {
+	dir := t.TempDir()
+	workspaceFile := filepath.Join(dir, "plan.json")
+	if err := os.WriteFile(workspaceFile, []byte(`[{"name": "google-cloud-wkt", "version": "1.0.0", "location": "src/wkt"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := &options{ProjectRoot: dir, Workspace: workspaceFile, ReverseDependencies: true}
+	if got, err := loadWorkspace(opts); err == nil {
+		t.Errorf("expected an error with -reverse-deps and a workspaces plan, got=%v", got)
+	}
+
+	metadataFile := filepath.Join(dir, "metadata.json")
+	if err := os.WriteFile(metadataFile, []byte(`{"packages": [], "workspace_members": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts.Workspace = metadataFile
+	if _, err := loadWorkspace(opts); err != nil {
+		t.Errorf("expected no error with -reverse-deps and cargo metadata, got=%v", err)
+	}
+}
+
+func TestParseCargoMetadataPublishAndMetadata(t *testing.T) {
+	input := `{
+		"packages": [
+			{"id": "a", "name": "crate-a", "version": "1.0.0", "manifest_path": "/src/a/Cargo.toml", "publish": null,
+			 "metadata": {"docs": {"rs": {"all-features": true}}},
+			 "features": {"unstable": [], "default": ["unstable"]},
+			 "dependencies": [
+				{"name": "crate-c", "kind": null},
+				{"name": "serde", "kind": null},
+				{"name": "tokio", "kind": "dev"},
+				{"name": "prost-build", "kind": "build"}
+			 ]},
+			{"id": "b", "name": "crate-b", "version": "1.0.0", "manifest_path": "/src/b/Cargo.toml", "publish": []},
+			{"id": "c", "name": "crate-c", "version": "1.0.0", "manifest_path": "/src/c/Cargo.toml", "publish": ["crates-io"]}
+		],
+		"workspace_members": ["a", "b", "c"]
+	}`
+	got, err := parseCargoMetadata([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, c := range got {
+		names = append(names, c.Name)
+	}
+	if diff := cmp.Diff([]string{"crate-a", "crate-c"}, names); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	docs, ok := got[0].PackageMetadata["docs"]
+	if !ok {
+		t.Fatalf("missing `docs` in package metadata: %v", got[0].PackageMetadata)
+	}
+	if diff := cmp.Diff(`{"rs": {"all-features": true}}`, string(docs)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"crate-c", "serde"}, got[0].Dependencies); diff != "" {
+		t.Errorf("mismatch in dependencies (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"default", "unstable"}, got[0].AvailableFeatures); diff != "" {
+		t.Errorf("mismatch in features (-want, +got):\n%s", diff)
+	}
+	if got[1].PackageMetadata != nil {
+		t.Errorf("expected no package metadata, got=%v", got[1].PackageMetadata)
+	}
+}
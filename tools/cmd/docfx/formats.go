@@ -0,0 +1,118 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// The range of rustdoc JSON `format_version` values supported by the
+// generator. Use the `-toolchain` flag to pick a nightly toolchain producing
+// one of these versions.
+//
+// The structs in `workspace.go` match `maxFormatVersion`. Older versions are
+// converted to that representation by the adapters in `formatAdapters`. Only
+// include versions verified with a fixture in `testdata/formats`, generated
+// by a nightly toolchain from the `testdata/formats/fmtprobe` crate.
+const (
+	minFormatVersion = 55
+	maxFormatVersion = 56
+)
+
+// formatAdapter upgrades a rustdoc JSON document from `version - 1` to
+// `version`.
+//
+// Adapters operate on the generic JSON representation, and only need to
+// handle the fields used by the generator.
+type formatAdapter struct {
+	version int
+	upgrade func(doc map[string]any) error
+}
+
+// formatAdapters must be sorted by version, each adapter is applied to all the
+// documents older than its version. To support a new format version, update
+// the structs in `workspace.go` and `maxFormatVersion`, and add an adapter
+// converting the previous version.
+//
+// Version 55 uses the same representation as version 56 for all the fields
+// used by the generator, it needs no adapter.
+var formatAdapters = []formatAdapter{}
+
+// unmarshalRustdoc decodes the rustdoc JSON in `jsonBytes` into `crate`.
+//
+// Returns an error if the `format_version` is not supported, as the structs
+// would silently ignore any renamed or restructured fields.
+func unmarshalRustdoc(crate *crate, jsonBytes []byte) error {
+	var header struct {
+		FormatVersion *int `json:"format_version"`
+	}
+	if err := json.Unmarshal(jsonBytes, &header); err != nil {
+		return fmt.Errorf("rustdoc JSON unmarshal error: %w", err)
+	}
+	if header.FormatVersion == nil {
+		return fmt.Errorf("missing format_version in rustdoc JSON")
+	}
+	if err := checkFormatVersion(*header.FormatVersion); err != nil {
+		return err
+	}
+	normalized, err := normalizeRustdoc(*header.FormatVersion, jsonBytes)
+	if err != nil {
+		return fmt.Errorf("error converting rustdoc JSON (format_version %d): %w", *header.FormatVersion, err)
+	}
+	if err := json.Unmarshal(normalized, crate); err != nil {
+		return fmt.Errorf("rustdoc JSON unmarshal error (format_version %d): %w", *header.FormatVersion, err)
+	}
+	return nil
+}
+
+func checkFormatVersion(v int) error {
+	if v < minFormatVersion || v > maxFormatVersion {
+		return fmt.Errorf("unsupported rustdoc JSON format_version %d, the supported versions are %d to %d, use -toolchain to select a nightly toolchain producing a supported version", v, minFormatVersion, maxFormatVersion)
+	}
+	return nil
+}
+
+// normalizeRustdoc converts the rustdoc JSON in `jsonBytes`, using format
+// `version`, to the representation expected by the structs in
+// `workspace.go`.
+func normalizeRustdoc(version int, jsonBytes []byte) ([]byte, error) {
+	var adapters []formatAdapter
+	for _, a := range formatAdapters {
+		if a.version > version {
+			adapters = append(adapters, a)
+		}
+	}
+	if len(adapters) == 0 {
+		// Avoid the round trip through the generic representation, the
+		// rustdoc JSON for large crates is hundreds of megabytes.
+		return jsonBytes, nil
+	}
+	// Preserve the exact value of all numbers, including the ids.
+	decoder := json.NewDecoder(bytes.NewReader(jsonBytes))
+	decoder.UseNumber()
+	var doc map[string]any
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	for _, a := range adapters {
+		if err := a.upgrade(doc); err != nil {
+			return nil, fmt.Errorf("error upgrading to format_version %d: %w", a.version, err)
+		}
+	}
+	doc["format_version"] = maxFormatVersion
+	return json.Marshal(doc)
+}
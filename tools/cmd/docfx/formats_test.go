@@ -0,0 +1,172 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUnmarshalRustdocFormatVersion(t *testing.T) {
+	for _, test := range []struct {
+		Input   string
+		WantErr string
+	}{
+		{`{"root": 1}`, "missing format_version"},
+		{`{"root": 1, "format_version": 1}`, "unsupported rustdoc JSON format_version 1"},
+		{fmt.Sprintf(`{"root": 1, "format_version": %d}`, maxFormatVersion+1), "use -toolchain"},
+		{fmt.Sprintf(`{"root": "bad", "format_version": %d}`, maxFormatVersion), "unmarshal error"},
+		{`not json`, "unmarshal error"},
+	} {
+		var got crate
+		err := unmarshalRustdoc(&got, []byte(test.Input))
+		if err == nil || !strings.Contains(err.Error(), test.WantErr) {
+			t.Errorf("expected an error containing %q for %s, got=%v", test.WantErr, test.Input, err)
+		}
+	}
+
+	var got crate
+	if err := unmarshalRustdoc(&got, []byte(fmt.Sprintf(`{"root": 42, "format_version": %d}`, maxFormatVersion))); err != nil {
+		t.Fatal(err)
+	}
+	if got.Root != 42 {
+		t.Errorf("mismatch in root, want=42, got=%d", got.Root)
+	}
+}
+
+func TestUnmarshalRustdocFormats(t *testing.T) {
+	// Each fixture is generated from the `testdata/formats/fmtprobe` crate
+	// with a toolchain producing that version, e.g. for version 56:
+	//   cargo +nightly-2025-09-26 rustdoc -Z unstable-options --output-format=json
+	// and for version 55:
+	//   RUSTC_BOOTSTRAP=1 cargo +1.90.0 rustdoc -Z unstable-options --output-format=json
+	for version := minFormatVersion; version <= maxFormatVersion; version++ {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			contents, err := os.ReadFile(fmt.Sprintf("testdata/formats/v%d.json", version))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := &crate{}
+			if err := unmarshalRustdoc(got, contents); err != nil {
+				t.Fatal(err)
+			}
+			if name := got.getRootName(); name != "fmtprobe" {
+				t.Fatalf("mismatch in root name, want=fmtprobe, got=%s", name)
+			}
+			ids := map[string]string{}
+			for id, item := range got.Index {
+				if got.Paths[id].CrateId == 0 && item.Name != "" {
+					ids[strings.Join(got.Paths[id].Path, "::")] = id
+				}
+			}
+			kinds := map[string]kind{
+				"fmtprobe":               crateKind,
+				"fmtprobe::inner":        moduleKind,
+				"fmtprobe::inner::Named": traitKind,
+				"fmtprobe::Secret":       structKind,
+				"fmtprobe::Unit":         structKind,
+				"fmtprobe::Tuple":        structKind,
+			}
+			for path, want := range kinds {
+				if got := got.getKind(ids[path]); got != want {
+					t.Errorf("mismatch in kind for %s, want=%s, got=%s", path, want, got)
+				}
+			}
+
+			secret := got.Index[ids["fmtprobe::Secret"]]
+			if diff := cmp.Diff(attributes{"#[non_exhaustive]"}, secret.Attrs); diff != "" {
+				t.Errorf("mismatch in Secret attributes (-want, +got):\n%s", diff)
+			}
+			if fields := secret.Inner.Struct.Kind.Plain.Fields; len(fields) != 1 || got.Index[idToString(fields[0])].Name != "name" {
+				t.Errorf("mismatch in Secret fields, got=%v", fields)
+			}
+			if !got.Index[ids["fmtprobe::Unit"]].Inner.Struct.Kind.Unit {
+				t.Errorf("expected Unit to be a unit struct")
+			}
+			if tuple := got.Index[ids["fmtprobe::Tuple"]].Inner.Struct.Kind.Tuple; len(tuple) != 1 || tuple[0] == nil {
+				t.Errorf("mismatch in Tuple fields, got=%v", tuple)
+			}
+			if got.Index[ids["fmtprobe::inner"]].Inner.Module.IsStripped {
+				t.Errorf("expected fmtprobe::inner to not be stripped")
+			}
+			var methods []string
+			for _, id := range got.Index[ids["fmtprobe::inner::Named"]].Inner.Trait.Items {
+				item := got.Index[idToString(id)]
+				if item.Inner.Function == nil {
+					t.Errorf("expected a function for trait item %s", item.Name)
+				}
+				methods = append(methods, item.Name)
+			}
+			slices.Sort(methods)
+			if diff := cmp.Diff([]string{"kind", "name"}, methods); diff != "" {
+				t.Errorf("mismatch in trait methods (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNormalizeRustdocWithoutAdapters(t *testing.T) {
+	for version := minFormatVersion; version <= maxFormatVersion; version++ {
+		input := []byte(fmt.Sprintf(`{"format_version": %d, "index": {"1": {"attrs": ["non_exhaustive"]}}}`, version))
+		got, err := normalizeRustdoc(version, input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(string(input), string(got)); diff != "" {
+			t.Errorf("version %d should not be modified (-want, +got):\n%s", version, diff)
+		}
+	}
+}
+
+func TestNormalizeRustdocAdapters(t *testing.T) {
+	saved := formatAdapters
+	t.Cleanup(func() { formatAdapters = saved })
+	formatAdapters = []formatAdapter{
+		{version: maxFormatVersion - 1, upgrade: func(doc map[string]any) error {
+			doc["first"] = true
+			return nil
+		}},
+		{version: maxFormatVersion, upgrade: func(doc map[string]any) error {
+			if doc["first"] != true {
+				return fmt.Errorf("the adapters must run in order")
+			}
+			doc["root"] = doc["renamed_root"]
+			delete(doc, "renamed_root")
+			return nil
+		}},
+	}
+	input := []byte(`{"format_version": 1, "renamed_root": 4294967295}`)
+	got, err := normalizeRustdoc(maxFormatVersion-2, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf(`{"first":true,"format_version":%d,"root":4294967295}`, maxFormatVersion)
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	// Only the adapters for newer versions apply.
+	if _, err := normalizeRustdoc(maxFormatVersion-1, input); err == nil {
+		t.Errorf("expected an error applying the second adapter alone")
+	}
+	if _, err := normalizeRustdoc(maxFormatVersion-2, []byte("not json")); err == nil {
+		t.Errorf("expected an error for invalid JSON")
+	}
+}
@@ -0,0 +1,613 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// The modules present in all generated (GAPIC) service crates.
+const (
+	gapicClientModule  = "client"
+	gapicBuilderModule = "builder"
+	gapicModelModule   = "model"
+)
+
+// gapicRpc describes a client method that starts an RPC.
+//
+// In generated crates each RPC is a method in the client struct, returning a
+// request builder. The request builder has a `with_request()` method to set
+// the request message and a `send()` method returning the response.
+type gapicRpc struct {
+	// The id of the client method.
+	Method string
+	// The id of the request builder struct.
+	Builder string
+	// The request message, or nil if it cannot be determined.
+	Request *typeEnum
+	// The response type, or nil if it cannot be determined.
+	Response *typeEnum
+}
+
+// isGapic returns true if the crate has the shape of a generated service
+// crate.
+//
+// The `.repo-metadata.json` file must declare a GAPIC library, and the crate
+// must have the `client`, `builder`, and `model` modules at its root.
+func (c *crate) isGapic() bool {
+	if c.Metadata == nil || !strings.HasPrefix(c.Metadata.LibraryType, "GAPIC") {
+		return false
+	}
+	return c.hasGapicModules()
+}
+
+// hasGapicModules returns true if the crate has the `client`, `builder`, and
+// `model` modules at its root.
+func (c *crate) hasGapicModules() bool {
+	modules := c.rootModules()
+	for _, name := range []string{gapicClientModule, gapicBuilderModule, gapicModelModule} {
+		if _, ok := modules[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// useGapicTOC returns true if the table of contents uses the sections for
+// generated crates. The `toc-grouping` override can force the grouping, as
+// long as the crate has the required modules.
+func (c *crate) useGapicTOC() bool {
+	switch c.Config.TocGrouping {
+	case tocGroupingGapic:
+		return c.hasGapicModules()
+	case tocGroupingModules:
+		return false
+	}
+	return c.isGapic()
+}
+
+// rootModules returns the ids of the modules at the root of the crate, indexed
+// by their name.
+func (c *crate) rootModules() map[string]string {
+	modules := map[string]string{}
+	root, ok := c.Index[idToString(c.Root)]
+	if !ok || root.Inner.Module == nil {
+		return modules
+	}
+	for _, child := range root.Inner.Module.Items {
+		childId := idToString(child)
+		if c.getKind(childId) == moduleKind {
+			modules[c.getName(childId)] = childId
+		}
+	}
+	return modules
+}
+
+// isInGapicModule returns true if the item is defined in (or under) one of the
+// modules at the root of a generated crate.
+func (c *crate) isInGapicModule(id, module string) bool {
+	path := c.Paths[id].Path
+	return len(path) > 2 && path[1] == module
+}
+
+// inherentFunctions returns the ids of the functions in the inherent
+// implementations of a struct, in the order they appear in the source.
+func (c *crate) inherentFunctions(id string) []string {
+	var functions []string
+	if c.Index[id].Inner.Struct == nil {
+		return functions
+	}
+	for _, implId := range c.Index[id].Inner.Struct.Impls {
+		impl := c.Index[idToString(implId)].Inner.Impl
+		if impl == nil || impl.Trait != nil || impl.BlanketImpl != nil || impl.IsSyntheic {
+			continue
+		}
+		for _, itemId := range impl.Items {
+			if c.getKind(idToString(itemId)) == functionKind {
+				functions = append(functions, idToString(itemId))
+			}
+		}
+	}
+	return functions
+}
+
+// findInherentFunction returns the id of the function called `name` in the
+// inherent implementations of a struct.
+func (c *crate) findInherentFunction(id, name string) (string, bool) {
+	functions := c.inherentFunctions(id)
+	idx := slices.IndexFunc(functions, func(f string) bool { return c.getName(f) == name })
+	if idx == -1 {
+		return "", false
+	}
+	return functions[idx], true
+}
+
+// isRequestBuilder returns true if `id` is a request builder in a generated
+// crate.
+func (c *crate) isRequestBuilder(id string) bool {
+	if c.getKind(id) != structKind || !c.isInGapicModule(id, gapicBuilderModule) {
+		return false
+	}
+	_, ok := c.findInherentFunction(id, "send")
+	return ok
+}
+
+// getGapicRpc returns the RPC started by the function `id`, if any.
+func (c *crate) getGapicRpc(id string) (*gapicRpc, bool) {
+	if !c.isGapic() || c.getKind(id) != functionKind {
+		return nil, false
+	}
+	output := c.Index[id].Inner.Function.Sig.Output
+	if output == nil {
+		return nil, false
+	}
+	builderId := idToString(output.ResolvedPath.Id)
+	if !c.isRequestBuilder(builderId) {
+		return nil, false
+	}
+	rpc := &gapicRpc{
+		Method:  id,
+		Builder: builderId,
+	}
+	if f, ok := c.findInherentFunction(builderId, "with_request"); ok {
+		rpc.Request = intoBoundType(c.Index[f].Inner.Function)
+	}
+	if f, ok := c.findInherentFunction(builderId, "send"); ok {
+		rpc.Response = resultType(c.Index[f].Inner.Function.Sig.Output)
+	}
+	return rpc, true
+}
+
+// intoBoundType returns `T` for functions with a generic parameter bounded by
+// `Into<T>`, such as the `with_request()` and `set_*()` functions in request
+// builders.
+func intoBoundType(f *function) *typeEnum {
+	for _, param := range f.Generics.Params {
+		if param.Kind.GenericParamDefType == nil {
+			continue
+		}
+		for _, bound := range param.Kind.GenericParamDefType.Bounds {
+			if bound.TraitBound == nil || !isPathNamed(bound.TraitBound.Trait.Path, "Into") {
+				continue
+			}
+			if args := bound.TraitBound.Trait.Args.AngleBracketed.Args; len(args) == 1 {
+				return &args[0].Type
+			}
+		}
+	}
+	return nil
+}
+
+// resultType returns `T` for `Result<T>` types.
+func resultType(t *typeEnum) *typeEnum {
+	if t == nil || !isPathNamed(t.ResolvedPath.Path, "Result") {
+		return nil
+	}
+	if args := t.ResolvedPath.Args.AngleBracketed.Args; len(args) != 0 {
+		return &args[0].Type
+	}
+	return nil
+}
+
+// isPathNamed returns true if the last element of a Rust path is `name`.
+func isPathNamed(path, name string) bool {
+	return path == name || strings.HasSuffix(path, "::"+name)
+}
+
+// typeLink formats a type as a link to its page, or as inline code if the
+// type is not documented in this crate.
+func (c *crate) typeLink(t *typeEnum) string {
+	if t == nil {
+		return ""
+	}
+	if t.ResolvedPath.Path != "" {
+		id := idToString(t.ResolvedPath.Id)
+		if _, ok := c.Index[id]; ok {
+			if uid, err := c.getDocfxUid(id); err == nil {
+				return xrefLink(c.getName(id), uid)
+			}
+		}
+	}
+	s, err := t.toString()
+	if err != nil || s == "" {
+		return ""
+	}
+	return fmt.Sprintf("`%s`", s)
+}
+
+// xrefLink formats a Markdown link to a DocFX uid.
+func xrefLink(name, uid string) string {
+	return fmt.Sprintf("[%s](xref:%s)", name, uid)
+}
+
+// rpcDetails formats the RPC details shown with each client method.
+func (c *crate) rpcDetails(rpc *gapicRpc) string {
+	lines := []string{"**RPC details**", ""}
+	if uid, err := c.getDocfxUid(rpc.Builder); err == nil {
+		lines = append(lines, fmt.Sprintf("- Request builder: %s", xrefLink(c.getName(rpc.Builder), uid)))
+	}
+	if link := c.typeLink(rpc.Request); link != "" {
+		lines = append(lines, fmt.Sprintf("- Request message: %s", link))
+	}
+	if link := c.typeLink(rpc.Response); link != "" {
+		lines = append(lines, fmt.Sprintf("- Response: %s", link))
+	}
+	if c.Metadata != nil && c.Metadata.ProductDocumentation != "" {
+		name := c.Metadata.NamePretty
+		if name == "" {
+			name = c.Metadata.ProductDocumentation
+		}
+		lines = append(lines, fmt.Sprintf("- Product documentation: [%s](%s)", name, c.Metadata.ProductDocumentation))
+	}
+	if uid, err := c.getDocfxUid(rpc.Builder); err == nil {
+		if callout := c.helpersCallout(uid, rpc.Builder); callout != "" {
+			lines = append(lines, "", callout)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// rpcTable formats a table with all the RPCs in a client.
+func (c *crate) rpcTable(parentUid, id string) string {
+	var rows []string
+	for _, f := range c.inherentFunctions(id) {
+		rpc, ok := c.getGapicRpc(f)
+		if !ok {
+			continue
+		}
+		builder := c.getName(rpc.Builder)
+		if uid, err := c.getDocfxUid(rpc.Builder); err == nil {
+			builder = xrefLink(builder, uid)
+		}
+		rows = append(rows, fmt.Sprintf("| %s | %s | %s | %s |",
+			xrefLink(c.getName(f), c.getDocfxUidWithParentPrefix(parentUid, f)),
+			builder, c.typeLink(rpc.Request), c.typeLink(rpc.Response)))
+	}
+	if len(rows) == 0 {
+		return ""
+	}
+	header := []string{
+		"**RPCs**",
+		"",
+		"| Method | Request builder | Request | Response |",
+		"| --- | --- | --- | --- |",
+	}
+	return strings.Join(append(header, rows...), "\n")
+}
+
+// regroupGapicTOC reorganizes the table of contents for generated crates.
+//
+// Instead of the module tree, the clients, request builders, model, and error
+// types get their own sections at the top of the table of contents.
+func regroupGapicTOC(c *crate, toc *docfxTableOfContent) {
+	var modules []*docfxTableOfContent
+	for _, m := range toc.Modules {
+		switch m.Name {
+		case gapicClientModule:
+			toc.Clients = append(toc.Clients, m.Structs...)
+		case gapicBuilderModule:
+			toc.RequestBuilders = append(toc.RequestBuilders, m.Modules...)
+		case gapicModelModule:
+			m.Name = "Model"
+			toc.Model = m
+		default:
+			modules = append(modules, m)
+		}
+	}
+	toc.Modules = modules
+
+	isError := func(e *docfxTableOfContent) bool { return strings.HasSuffix(e.Name, "Error") }
+	toc.Errors = append(toc.Errors, slices.DeleteFunc(slices.Clone(toc.Structs), func(e *docfxTableOfContent) bool { return !isError(e) })...)
+	toc.Errors = append(toc.Errors, slices.DeleteFunc(slices.Clone(toc.Enums), func(e *docfxTableOfContent) bool { return !isError(e) })...)
+	toc.Structs = slices.DeleteFunc(toc.Structs, isError)
+	toc.Enums = slices.DeleteFunc(toc.Enums, isError)
+
+	// The generated crates re-export the error types from `google-cloud-gax`.
+	root := c.Index[idToString(c.Root)].Inner.Module
+	if root == nil {
+		return
+	}
+	for _, child := range root.Items {
+		u := c.Index[idToString(child)].Inner.Use
+		if u == nil || (u.Name != "Error" && u.Name != "Result") {
+			continue
+		}
+		if href, ok := c.externalDocsUrl(u); ok {
+			toc.Errors = append(toc.Errors, &docfxTableOfContent{Name: u.Name, Href: href})
+		}
+	}
+	slices.SortStableFunc(toc.Errors, func(a, b *docfxTableOfContent) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
+// externalDocsUrl returns the URL for the documentation of an item re-exported
+// from another crate.
+func (c *crate) externalDocsUrl(u *use) (string, bool) {
+	if u.Id == nil || u.IsGlob {
+		return "", false
+	}
+	summary, ok := c.Paths[idToString(*u.Id)]
+	if !ok || summary.CrateId == 0 {
+		return "", false
+	}
+	external, ok := c.ExternalCrates[idToString(summary.CrateId)]
+	if !ok {
+		return "", false
+	}
+	// The source path starts with the name of the crate in `Cargo.toml`,
+	// which may be renamed. Use the actual crate name instead.
+	segments := strings.Split(u.Source, "::")
+	segments[0] = external.Name
+	root := fmt.Sprintf("https://docs.rs/%s/latest/", strings.ReplaceAll(external.Name, "_", "-"))
+	if external.HtmlRootUrl != nil {
+		root = *external.HtmlRootUrl
+	}
+	dir := strings.Join(segments[:len(segments)-1], "/")
+	name := segments[len(segments)-1]
+	switch summary.Kind {
+	case "module":
+		return fmt.Sprintf("%s%s/%s/index.html", root, dir, name), true
+	case "type_alias":
+		return fmt.Sprintf("%s%s/type.%s.html", root, dir, name), true
+	case "function":
+		return fmt.Sprintf("%s%s/fn.%s.html", root, dir, name), true
+	default:
+		return fmt.Sprintf("%s%s/%s.%s.html", root, dir, summary.Kind, name), true
+	}
+}
+
+// builderSetter describes the request builder functions setting a request
+// field.
+type builderSetter struct {
+	// The name of the field.
+	Field string
+	// The ids of the functions setting the field, e.g. `set_foo()` and
+	// `set_or_clear_foo()`.
+	Functions []string
+	// The id of the field in the request message, if found.
+	FieldId string
+}
+
+// isBuilderSetter returns true for request builder functions that set a
+// request field.
+func isBuilderSetter(name string) bool {
+	switch name {
+	case "with_request", "with_options":
+		return false
+	}
+	return strings.HasPrefix(name, "set_") || strings.HasPrefix(name, "with_")
+}
+
+// setterField returns the name of the field set by a request builder function.
+func setterField(name string) string {
+	for _, prefix := range []string{"set_or_clear_", "set_", "with_"} {
+		if field, ok := strings.CutPrefix(name, prefix); ok {
+			return field
+		}
+	}
+	return name
+}
+
+// builderSetters returns the setters in a request builder, sorted by field
+// name.
+func (c *crate) builderSetters(id string) []*builderSetter {
+	fields := map[string]string{}
+	if f, ok := c.findInherentFunction(id, "with_request"); ok {
+		if request := intoBoundType(c.Index[f].Inner.Function); request != nil {
+			requestId := idToString(request.ResolvedPath.Id)
+			if s := c.Index[requestId].Inner.Struct; s != nil {
+				for _, fieldId := range s.Kind.Plain.Fields {
+					fields[c.getName(idToString(fieldId))] = idToString(fieldId)
+				}
+			}
+		}
+	}
+
+	var setters []*builderSetter
+	byField := map[string]*builderSetter{}
+	for _, f := range c.inherentFunctions(id) {
+		if !isBuilderSetter(c.getName(f)) {
+			continue
+		}
+		field := setterField(c.getName(f))
+		if s, ok := byField[field]; ok {
+			s.Functions = append(s.Functions, f)
+			continue
+		}
+		s := &builderSetter{Field: field, Functions: []string{f}, FieldId: fields[field]}
+		byField[field] = s
+		setters = append(setters, s)
+	}
+	slices.SortStableFunc(setters, func(a, b *builderSetter) int {
+		return strings.Compare(a.Field, b.Field)
+	})
+	return setters
+}
+
+// setterTable formats a table with the fields settable via a request builder.
+//
+// The table replaces the setter functions in the list of children. Each row
+// links to the full documentation for the setter functions.
+func (c *crate) setterTable(parentUid string, setters []*builderSetter) string {
+	lines := []string{
+		"**Request fields**",
+		"",
+		"| Field | Setters | Type | Description |",
+		"| --- | --- | --- | --- |",
+	}
+	for _, s := range setters {
+		var links []string
+		for _, f := range s.Functions {
+			links = append(links, xrefLink(c.getName(f), c.getDocfxUidWithParentPrefix(parentUid, f)))
+		}
+		fieldType := ""
+		description := docSummary(c.Index[s.Functions[0]].Docs)
+		if s.FieldId != "" {
+			if t, err := c.Index[s.FieldId].Inner.StructField.toString(); err == nil {
+				fieldType = fmt.Sprintf("`%s`", t)
+			}
+			if docs := docSummary(c.Index[s.FieldId].Docs); docs != "" {
+				description = docs
+			}
+		} else if t := intoBoundType(c.Index[s.Functions[0]].Inner.Function); t != nil {
+			if s, err := t.toString(); err == nil {
+				fieldType = fmt.Sprintf("`%s`", s)
+			}
+		}
+		lines = append(lines, fmt.Sprintf("| %s | %s | %s | %s |", s.Field, strings.Join(links, ", "), fieldType, description))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// collapseBuilderSetters replaces the setter functions in the children of a
+// request builder page with a table of settable fields.
+//
+// Request builders for large RPCs have dozens of setters, listing each one as
+// a child makes the page hard to navigate. The items for each setter are still
+// generated, and the table links to them.
+func collapseBuilderSetters(c *crate, id string, parent *docfxItem) {
+	setters := c.builderSetters(id)
+	if len(setters) == 0 {
+		return
+	}
+	collapsed := map[string]bool{}
+	for _, s := range setters {
+		for _, f := range s.Functions {
+			collapsed[c.getDocfxUidWithParentPrefix(parent.Uid, f)] = true
+		}
+	}
+	parent.Children = slices.DeleteFunc(parent.Children, func(uid string) bool { return collapsed[uid] })
+	parent.HasChildren = len(parent.Children) != 0
+	parent.appendRemarks(c.setterTable(parent.Uid, setters))
+}
+
+// longRunning describes the helpers in request builders for RPCs returning
+// long-running operations.
+type longRunning struct {
+	// The id of the `poller()` function.
+	Poller string
+	// The trait implemented by the poller, e.g. `google_cloud_lro::Poller`.
+	Trait string
+	// The type of the operation result.
+	Result *typeEnum
+	// The type of the operation metadata.
+	Metadata *typeEnum
+}
+
+// pagination describes the helpers in request builders for RPCs returning
+// paginated lists.
+type pagination struct {
+	// The ids of the `by_page()` and `by_item()` functions, `ByItem` may be
+	// empty.
+	ByPage string
+	ByItem string
+	// The traits implemented by the paginators.
+	PageTrait string
+	ItemTrait string
+	// The type of each page.
+	Response *typeEnum
+}
+
+// getLongRunning returns the long-running operation helpers for a request
+// builder, if any.
+//
+// These request builders have a `poller()` function returning an
+// `impl Poller<ResultType, MetadataType>`.
+func (c *crate) getLongRunning(builderId string) (*longRunning, bool) {
+	f, ok := c.findInherentFunction(builderId, "poller")
+	if !ok {
+		return nil, false
+	}
+	trait, args := implTraitArgs(c.Index[f].Inner.Function.Sig.Output, "Poller")
+	if len(args) != 2 {
+		return nil, false
+	}
+	return &longRunning{Poller: f, Trait: trait, Result: &args[0].Type, Metadata: &args[1].Type}, true
+}
+
+// getPagination returns the pagination helpers for a request builder, if any.
+//
+// These request builders have a `by_page()` function returning an
+// `impl Paginator<ResponseType, ErrorType>`, and usually a `by_item()`
+// function returning an `impl ItemPaginator<ResponseType, ErrorType>`.
+func (c *crate) getPagination(builderId string) (*pagination, bool) {
+	f, ok := c.findInherentFunction(builderId, "by_page")
+	if !ok {
+		return nil, false
+	}
+	trait, args := implTraitArgs(c.Index[f].Inner.Function.Sig.Output, "Paginator")
+	if len(args) == 0 {
+		return nil, false
+	}
+	p := &pagination{ByPage: f, PageTrait: trait, Response: &args[0].Type}
+	if f, ok := c.findInherentFunction(builderId, "by_item"); ok {
+		if trait, _ := implTraitArgs(c.Index[f].Inner.Function.Sig.Output, "ItemPaginator"); trait != "" {
+			p.ByItem = f
+			p.ItemTrait = trait
+		}
+	}
+	return p, true
+}
+
+// implTraitArgs finds the trait called `name` in an `impl Trait` type, and
+// returns its full path and generic arguments.
+func implTraitArgs(t *typeEnum, name string) (string, []genericArg) {
+	if t == nil {
+		return "", nil
+	}
+	for _, bound := range t.ImplTrait {
+		if bound.TraitBound != nil && isPathNamed(bound.TraitBound.Trait.Path, name) {
+			return bound.TraitBound.Trait.Path, bound.TraitBound.Trait.Args.AngleBracketed.Args
+		}
+	}
+	return "", nil
+}
+
+// helpersCallout formats a note pointing readers to the helpers for
+// long-running operations and pagination.
+func (c *crate) helpersCallout(builderUid, builderId string) string {
+	var lines []string
+	if lro, ok := c.getLongRunning(builderId); ok {
+		lines = append(lines,
+			"This RPC starts a long-running operation.",
+			fmt.Sprintf("Use %s to get a `%s`.", xrefLink(c.getName(lro.Poller)+"()", c.getDocfxUidWithParentPrefix(builderUid, lro.Poller)), lro.Trait),
+			fmt.Sprintf("Call `until_done()` on the poller to wait for the result, of type %s.", c.typeLink(lro.Result)),
+			fmt.Sprintf("Call `poll()` to also get the operation metadata, of type %s.", c.typeLink(lro.Metadata)),
+		)
+	}
+	if p, ok := c.getPagination(builderId); ok {
+		if len(lines) != 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "This RPC returns a paginated list.")
+		if p.ByItem != "" {
+			lines = append(lines, fmt.Sprintf("Use %s to stream each item in the list, this requires the `%s` trait.",
+				xrefLink(c.getName(p.ByItem)+"()", c.getDocfxUidWithParentPrefix(builderUid, p.ByItem)), p.ItemTrait))
+		}
+		lines = append(lines, fmt.Sprintf("Use %s to stream each page, of type %s, this requires the `%s` trait.",
+			xrefLink(c.getName(p.ByPage)+"()", c.getDocfxUidWithParentPrefix(builderUid, p.ByPage)), c.typeLink(p.Response), p.PageTrait))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	callout := []string{"> [!NOTE]"}
+	for _, line := range lines {
+		callout = append(callout, strings.TrimRight("> "+line, " "))
+	}
+	return strings.Join(callout, "\n")
+}
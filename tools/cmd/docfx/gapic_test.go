@@ -0,0 +1,369 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	fspath "path"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIsGapic(t *testing.T) {
+	input, err := testDataPublicCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if input.isGapic() {
+		t.Errorf("expected isGapic() == false without repo metadata")
+	}
+	input.Metadata = &repoMetadata{LibraryType: "OTHER"}
+	if input.isGapic() {
+		t.Errorf("expected isGapic() == false for non-GAPIC library type")
+	}
+	input.Metadata = testGapicMetadata()
+	if !input.isGapic() {
+		t.Errorf("expected isGapic() == true for GAPIC library type")
+	}
+}
+
+func TestGetGapicRpc(t *testing.T) {
+	input, err := testDataPublicCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	input.Metadata = testGapicMetadata()
+	clientUid := "struct.google_cloud_security_publicca_v1.client.PublicCertificateAuthorityService"
+	clientId := findIdByUid(t, input, clientUid)
+	var got []string
+	for _, f := range input.inherentFunctions(clientId) {
+		rpc, ok := input.getGapicRpc(f)
+		if !ok {
+			continue
+		}
+		builderUid, err := input.getDocfxUid(rpc.Builder)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, input.getName(rpc.Method), builderUid, rpc.Request.ResolvedPath.Path, rpc.Response.ResolvedPath.Path)
+	}
+	want := []string{
+		"create_external_account_key",
+		"struct.google_cloud_security_publicca_v1.builder.public_certificate_authority_service.CreateExternalAccountKey",
+		"crate::model::CreateExternalAccountKeyRequest",
+		"crate::model::ExternalAccountKey",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatched RPCs (-want +got):\n%s", diff)
+	}
+}
+
+func TestRenderReferenceGapicClient(t *testing.T) {
+	input, err := testDataPublicCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	input.Metadata = testGapicMetadata()
+	outDir := t.TempDir()
+	wantUid := "struct.google_cloud_security_publicca_v1.client.PublicCertificateAuthorityService"
+	id := findIdByUid(t, input, wantUid)
+	if err := renderReference(input, id, outDir); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := os.ReadFile(fspath.Join(outDir, fmt.Sprintf("%s.yml", wantUid)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(contents), "\n")
+	idx := slices.Index(lines, "    **RPCs**")
+	if idx == -1 {
+		t.Fatalf("missing RPC table in output YAML %s", contents)
+	}
+	want := []string{
+		"    **RPCs**",
+		"    ",
+		"    | Method | Request builder | Request | Response |",
+		"    | --- | --- | --- | --- |",
+		"    | [create_external_account_key](xref:struct.google_cloud_security_publicca_v1.client.PublicCertificateAuthorityService.create_external_account_key) | [CreateExternalAccountKey](xref:struct.google_cloud_security_publicca_v1.builder.public_certificate_authority_service.CreateExternalAccountKey) | [CreateExternalAccountKeyRequest](xref:struct.google_cloud_security_publicca_v1.model.CreateExternalAccountKeyRequest) | [ExternalAccountKey](xref:struct.google_cloud_security_publicca_v1.model.ExternalAccountKey) |",
+	}
+	if diff := cmp.Diff(want, lines[idx:idx+len(want)]); diff != "" {
+		t.Errorf("mismatched RPC table in generated YAML (-want +got):\n%s", diff)
+	}
+
+	idx = slices.Index(lines, "    **RPC details**")
+	if idx == -1 {
+		t.Fatalf("missing RPC details in output YAML %s", contents)
+	}
+	want = []string{
+		"    **RPC details**",
+		"    ",
+		"    - Request builder: [CreateExternalAccountKey](xref:struct.google_cloud_security_publicca_v1.builder.public_certificate_authority_service.CreateExternalAccountKey)",
+		"    - Request message: [CreateExternalAccountKeyRequest](xref:struct.google_cloud_security_publicca_v1.model.CreateExternalAccountKeyRequest)",
+		"    - Response: [ExternalAccountKey](xref:struct.google_cloud_security_publicca_v1.model.ExternalAccountKey)",
+		"    - Product documentation: [Public Certificate Authority](https://cloud.google.com/certificate-manager/)",
+	}
+	if diff := cmp.Diff(want, lines[idx:idx+len(want)]); diff != "" {
+		t.Errorf("mismatched RPC details in generated YAML (-want +got):\n%s", diff)
+	}
+}
+
+func TestRenderTocGapic(t *testing.T) {
+	input, err := testDataPublicCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	input.Metadata = testGapicMetadata()
+	outDir := t.TempDir()
+	toc, err := computeTOC(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := renderTOC(toc, outDir); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := os.ReadFile(fspath.Join(outDir, "toc.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Split(string(contents), "\n")
+	want := []string{
+		"### YamlMime:TableOfContent",
+		"- uid: crate.google_cloud_security_publicca_v1",
+		"  name: google_cloud_security_publicca_v1",
+		"  items:",
+		"  - href: overview.md",
+		"    name: Overview",
+		"  - name: Clients",
+		"    items:",
+		"    - uid: struct.google_cloud_security_publicca_v1.client.PublicCertificateAuthorityService",
+		"      name: PublicCertificateAuthorityService",
+		"  - name: Request Builders",
+		"    items:",
+		"    - uid: module.google_cloud_security_publicca_v1.builder.public_certificate_authority_service",
+		"      name: public_certificate_authority_service",
+		"      items:",
+		"      - name: Structs",
+		"        items:",
+		"        - uid: struct.google_cloud_security_publicca_v1.builder.public_certificate_authority_service.CreateExternalAccountKey",
+		"          name: CreateExternalAccountKey",
+		"      - name: Type Aliases",
+		"        items:",
+		"        - uid: typealias.google_cloud_security_publicca_v1.builder.public_certificate_authority_service.ClientBuilder",
+		"          name: ClientBuilder",
+		"  - uid: module.google_cloud_security_publicca_v1.model",
+		"    name: Model",
+		"    items:",
+		"    - name: Structs",
+		"      items:",
+		"      - uid: struct.google_cloud_security_publicca_v1.model.CreateExternalAccountKeyRequest",
+		"        name: CreateExternalAccountKeyRequest",
+		"      - uid: struct.google_cloud_security_publicca_v1.model.ExternalAccountKey",
+		"        name: ExternalAccountKey",
+		"    - name: Type Aliases",
+		"      items:",
+		"      - uid: typealias.google_cloud_security_publicca_v1.model.UInt64Value",
+		"        name: UInt64Value",
+		"  - name: Errors",
+		"    items:",
+		"    - href: https://docs.rs/google-cloud-gax/latest/google_cloud_gax/error/struct.Error.html",
+		"      name: Error",
+		"    - href: https://docs.rs/google-cloud-gax/latest/google_cloud_gax/type.Result.html",
+		"      name: Result",
+		"  - name: Modules",
+		"    items:",
+		"    - uid: module.google_cloud_security_publicca_v1.stub",
+		"      name: stub",
+		"      items:",
+		"      - name: Traits",
+		"        items:",
+		"        - uid: trait.google_cloud_security_publicca_v1.stub.PublicCertificateAuthorityService",
+		"          name: PublicCertificateAuthorityService",
+		"",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatched TOC lines in generated YAML (-want +got):\n%s", diff)
+	}
+}
+
+func TestUseGapicTOC(t *testing.T) {
+	input, err := testDataPublicCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		Metadata    *repoMetadata
+		TocGrouping string
+		Want        bool
+	}{
+		{nil, "", false},
+		{testGapicMetadata(), "", true},
+		{testGapicMetadata(), tocGroupingModules, false},
+		{nil, tocGroupingGapic, true},
+		{&repoMetadata{LibraryType: "OTHER"}, tocGroupingGapic, true},
+	} {
+		input.Metadata = test.Metadata
+		input.Config.TocGrouping = test.TocGrouping
+		if got := input.useGapicTOC(); got != test.Want {
+			t.Errorf("mismatch for metadata=%v, toc-grouping=%q: want=%v, got=%v", test.Metadata, test.TocGrouping, test.Want, got)
+		}
+	}
+
+	// The override cannot force the grouping for crates without the modules.
+	other := &crate{
+		Root:   1,
+		Index:  map[string]item{"1": {Name: "root"}},
+		Config: crateConfig{TocGrouping: tocGroupingGapic},
+	}
+	if other.useGapicTOC() {
+		t.Errorf("expected no gapic TOC for a crate without the gapic modules")
+	}
+}
+
+func testGapicMetadata() *repoMetadata {
+	return &repoMetadata{
+		ApiShortName:         "publicca",
+		NamePretty:           "Public Certificate Authority",
+		LibraryType:          "GAPIC_AUTO",
+		ProductDocumentation: "https://cloud.google.com/certificate-manager/",
+	}
+}
+
+func TestRenderReferenceBuilderSetters(t *testing.T) {
+	input, err := testDataPublicCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	input.Metadata = testGapicMetadata()
+	outDir := t.TempDir()
+	wantUid := "struct.google_cloud_security_publicca_v1.builder.public_certificate_authority_service.CreateExternalAccountKey"
+	id := findIdByUid(t, input, wantUid)
+	if err := renderReference(input, id, outDir); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := os.ReadFile(fspath.Join(outDir, fmt.Sprintf("%s.yml", wantUid)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(contents), "\n")
+	idx := slices.Index(lines, "  children:")
+	if idx == -1 {
+		t.Fatalf("missing children in output YAML %s", contents)
+	}
+	want := []string{
+		"  children:",
+		"  - " + wantUid + ".with_request",
+		"  - " + wantUid + ".with_options",
+		"  - " + wantUid + ".send",
+		"  summary: |",
+	}
+	if diff := cmp.Diff(want, lines[idx:idx+len(want)]); diff != "" {
+		t.Errorf("mismatched children in generated YAML (-want +got):\n%s", diff)
+	}
+
+	idx = slices.Index(lines, "    **Request fields**")
+	if idx == -1 {
+		t.Fatalf("missing setter table in output YAML %s", contents)
+	}
+	want = []string{
+		"    **Request fields**",
+		"    ",
+		"    | Field | Setters | Type | Description |",
+		"    | --- | --- | --- | --- |",
+		"    | external_account_key | [set_external_account_key](xref:" + wantUid + ".set_external_account_key), [set_or_clear_external_account_key](xref:" + wantUid + ".set_or_clear_external_account_key) | `std::option::Option<crate::model::ExternalAccountKey>` | Required. The external account key to create. |",
+		"    | parent | [set_parent](xref:" + wantUid + ".set_parent) | `std::string::String` | Required. The parent resource where this external_account_key will be created. |",
+	}
+	if diff := cmp.Diff(want, lines[idx:idx+len(want)]); diff != "" {
+		t.Errorf("mismatched setter table in generated YAML (-want +got):\n%s", diff)
+	}
+
+	// The setters are still documented, so the links in the table work.
+	if !slices.Contains(lines, "- uid: "+wantUid+".set_parent") {
+		t.Errorf("missing item for set_parent in output YAML %s", contents)
+	}
+}
+
+func TestHelpersCallout(t *testing.T) {
+	input, err := testDataPublicCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	input.Metadata = testGapicMetadata()
+	builderUid := "struct.google_cloud_security_publicca_v1.builder.public_certificate_authority_service.CreateExternalAccountKey"
+	builderId := findIdByUid(t, input, builderUid)
+	if got := input.helpersCallout(builderUid, builderId); got != "" {
+		t.Errorf("expected no callout for simple RPCs, got=%q", got)
+	}
+
+	key := typeEnum{ResolvedPath: path{Path: "crate::model::ExternalAccountKey", Id: 7}}
+	request := typeEnum{ResolvedPath: path{Path: "crate::model::CreateExternalAccountKeyRequest", Id: 103}}
+	addTestFunction(input, builderId, "9001", "poller", testImplTrait("google_cloud_lro::Poller", key, request))
+	addTestFunction(input, builderId, "9002", "by_page", testImplTrait("google_cloud_gax::paginator::Paginator", key, typeEnum{ResolvedPath: path{Path: "crate::Error"}}))
+	addTestFunction(input, builderId, "9003", "by_item", testImplTrait("google_cloud_gax::paginator::ItemPaginator", key, typeEnum{ResolvedPath: path{Path: "crate::Error"}}))
+
+	got := strings.Split(input.helpersCallout(builderUid, builderId), "\n")
+	want := []string{
+		"> [!NOTE]",
+		"> This RPC starts a long-running operation.",
+		"> Use [poller()](xref:" + builderUid + ".poller) to get a `google_cloud_lro::Poller`.",
+		"> Call `until_done()` on the poller to wait for the result, of type [ExternalAccountKey](xref:struct.google_cloud_security_publicca_v1.model.ExternalAccountKey).",
+		"> Call `poll()` to also get the operation metadata, of type [CreateExternalAccountKeyRequest](xref:struct.google_cloud_security_publicca_v1.model.CreateExternalAccountKeyRequest).",
+		">",
+		"> This RPC returns a paginated list.",
+		"> Use [by_item()](xref:" + builderUid + ".by_item) to stream each item in the list, this requires the `google_cloud_gax::paginator::ItemPaginator` trait.",
+		"> Use [by_page()](xref:" + builderUid + ".by_page) to stream each page, of type [ExternalAccountKey](xref:struct.google_cloud_security_publicca_v1.model.ExternalAccountKey), this requires the `google_cloud_gax::paginator::Paginator` trait.",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatched callout (-want +got):\n%s", diff)
+	}
+
+	outDir := t.TempDir()
+	clientUid := "struct.google_cloud_security_publicca_v1.client.PublicCertificateAuthorityService"
+	if err := renderReference(input, findIdByUid(t, input, clientUid), outDir); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := os.ReadFile(fspath.Join(outDir, fmt.Sprintf("%s.yml", clientUid)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(contents), "    > This RPC starts a long-running operation.\n") {
+		t.Errorf("missing callout in client page %s", contents)
+	}
+}
+
+// addTestFunction adds a function to the inherent implementation of a struct.
+func addTestFunction(c *crate, structId, id, name string, output *typeEnum) {
+	c.Index[id] = item{Name: name, Inner: itemEnum{Function: &function{Sig: functionSignature{Output: output}}}}
+	for _, implId := range c.Index[structId].Inner.Struct.Impls {
+		impl := c.Index[idToString(implId)].Inner.Impl
+		if impl.Trait == nil && impl.BlanketImpl == nil && !impl.IsSyntheic {
+			n, _ := strconv.ParseUint(id, 10, 32)
+			impl.Items = append(impl.Items, uint32(n))
+			return
+		}
+	}
+}
+
+func testImplTrait(trait string, args ...typeEnum) *typeEnum {
+	p := path{Path: trait}
+	for _, a := range args {
+		p.Args.AngleBracketed.Args = append(p.Args.AngleBracketed.Args, genericArg{Type: a})
+	}
+	return &typeEnum{ImplTrait: []genericBound{{TraitBound: &traitBound{Trait: p}}}}
+}
@@ -17,6 +17,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"slices"
 	"strings"
 	"time"
@@ -27,12 +28,18 @@ type docfxMetadata struct {
 	Version           string
 	UpdateTimeSeconds int64
 	UpdateTimeNano    int
+	ProductPage       string
+	IssueTracker      string
 }
 
 func newDocfxMetadata(c *crate) (*docfxMetadata, error) {
 	d := new(docfxMetadata)
 	d.Name = c.getRootName()
 	d.Version = c.Version
+	if c.Metadata != nil {
+		d.ProductPage = c.Metadata.ProductDocumentation
+		d.IssueTracker = c.Metadata.IssueTracker
+	}
 	now := time.Now().UTC()
 	d.UpdateTimeSeconds = now.Unix()
 	d.UpdateTimeNano = now.Nanosecond()
@@ -44,6 +51,8 @@ type docfxManagedReference struct {
 	Items         []docfxItem
 	HasReferences bool
 	References    []docfxReference
+	// docs is used to process all the docstrings in the page.
+	docs *docContext
 }
 
 func (mangedReference *docfxManagedReference) appendItem(item *docfxItem) {
@@ -65,6 +74,10 @@ type docfxItem struct {
 	Uid         string
 	Name        string
 	Summary     string
+	Remarks     string
+	Examples    []docfxExample
+	Errors      string
+	Panics      string
 	Type        string
 	HasChildren bool
 	Children    []string
@@ -77,6 +90,81 @@ func (item docfxItem) SummaryLines() []string {
 	return strings.Split(item.Summary, "\n")
 }
 
+// RemarksLines splits the remarks by lines so the mustache templates can
+// properly indent each line.
+func (item docfxItem) RemarksLines() []string {
+	return strings.Split(item.Remarks, "\n")
+}
+
+// ErrorsLines splits the errors section by lines so the mustache templates
+// can properly indent each line.
+func (item docfxItem) ErrorsLines() []string {
+	return strings.Split(item.Errors, "\n")
+}
+
+// PanicsLines splits the panics section by lines so the mustache templates
+// can properly indent each line.
+func (item docfxItem) PanicsLines() []string {
+	return strings.Split(item.Panics, "\n")
+}
+
+// HasExamples returns true if the item has examples, the mustache templates
+// use this to avoid empty sections.
+func (item docfxItem) HasExamples() bool {
+	return len(item.Examples) != 0
+}
+
+// setDocString splits a processed docstring into the summary, remarks,
+// examples, errors, and panics sections.
+func (item *docfxItem) setDocString(docs string) {
+	sections := splitDocString(docs)
+	item.Summary = sections.Summary
+	item.Remarks = sections.Remarks
+	item.Examples = nil
+	for _, e := range sections.Examples {
+		item.Examples = append(item.Examples, docfxExample{Content: e})
+	}
+	item.Errors = sections.Errors
+	item.Panics = sections.Panics
+}
+
+// prependSummary inserts `s`, e.g. a signature, before the summary.
+func (item *docfxItem) prependSummary(s string) {
+	item.Summary = joinParagraphs(s, item.Summary)
+}
+
+// prependRemarks inserts `s` before any existing remarks.
+func (item *docfxItem) prependRemarks(s string) {
+	item.Remarks = joinParagraphs(s, item.Remarks)
+}
+
+// appendRemarks adds `s`, e.g. a table, after any existing remarks.
+func (item *docfxItem) appendRemarks(s string) {
+	item.Remarks = joinParagraphs(item.Remarks, s)
+}
+
+func joinParagraphs(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	}
+	return fmt.Sprintf("%s\n\n%s", a, b)
+}
+
+// docfxExample is a context for the mustache templates, each example is
+// rendered as a separate entry in the `example` list.
+type docfxExample struct {
+	Content string
+}
+
+// Lines splits the example by lines so the mustache templates can properly
+// indent each line.
+func (e docfxExample) Lines() []string {
+	return strings.Split(e.Content, "\n")
+}
+
 type docfxSyntax struct {
 	Content       string
 	HasParameters bool
@@ -91,7 +179,7 @@ type docfxParameter struct {
 	VarType     string
 }
 
-func newDocfxItem(c *crate, id string) (*docfxItem, error) {
+func newDocfxItem(c *crate, page *docfxManagedReference, id string) (*docfxItem, error) {
 	var errs []error
 
 	r := new(docfxItem)
@@ -102,10 +190,11 @@ func newDocfxItem(c *crate, id string) (*docfxItem, error) {
 	}
 	r.Uid = uid
 	r.Type = c.getKind(id).String()
-	r.Summary, err = c.getDocString(id)
+	docs, err := c.getDocString(page.docs, id)
 	if err != nil {
 		errs = append(errs, err)
 	}
+	r.setDocString(docs)
 
 	if len(errs) > 0 {
 		return nil, fmt.Errorf("errors creating new DocfxItem docfx yml files for id %s: %w", id, errors.Join(errs...))
@@ -120,7 +209,7 @@ func processTrait(c *crate, id string, page *docfxManagedReference, parent *docf
 		kind := c.getKind(referenceId)
 		switch kind {
 		case functionKind:
-			function, err := newDocfxItemFromFunction(c, parent, referenceId)
+			function, err := newDocfxItemFromFunction(c, page, parent, referenceId)
 			if err != nil {
 				return fmt.Errorf("error processing trait item with id %s: %w", id, err)
 			}
@@ -156,6 +245,17 @@ func processModule(c *crate, id string, page *docfxManagedReference, parent *doc
 		if err != nil {
 			return err
 		}
+		if kind == functionKind {
+			// Free functions do not get their own page, they are documented
+			// as items in the page of the containing module.
+			function, err := newDocfxItemFromFunction(c, page, parent, referenceId)
+			if err != nil {
+				return fmt.Errorf("error processing module item with id %s: %w", id, err)
+			}
+			function.Uid = uid
+			function.Type = "function"
+			page.appendItem(function)
+		}
 		reference.Uid = uid
 		reference.Name = c.getName(referenceId)
 		reference.IsExternal = false
@@ -164,15 +264,71 @@ func processModule(c *crate, id string, page *docfxManagedReference, parent *doc
 		parent.appendChildren(reference.Uid)
 		page.appendReference(reference)
 	}
+	parent.appendRemarks(c.moduleSummaryTables(id))
 	return nil
 }
 
+// moduleSummaryGroups defines the order and titles of the summary tables in
+// module pages.
+var moduleSummaryGroups = []struct {
+	kind  kind
+	title string
+}{
+	{moduleKind, "Modules"},
+	{structKind, "Structs"},
+	{enumKind, "Enums"},
+	{traitKind, "Traits"},
+	{typeAliasKind, "Type Aliases"},
+	{functionKind, "Functions"},
+	{macroKind, "Macros"},
+}
+
+// moduleSummaryTables formats the tables listing the children of a module,
+// grouped by kind. Each row contains the name of the child and the first
+// sentence of its documentation, similar to the module index in rustdoc.
+func (c *crate) moduleSummaryTables(id string) string {
+	rows := map[kind][]string{}
+	items := slices.Clone(c.Index[id].Inner.Module.Items)
+	slices.SortFunc(items, func(a, b Id) int {
+		return strings.Compare(c.getName(idToString(a)), c.getName(idToString(b)))
+	})
+	for _, itemId := range items {
+		childId := idToString(itemId)
+		kind := c.getKind(childId)
+		name := fmt.Sprintf("`%s`", c.getName(childId))
+		switch kind {
+		case moduleKind, structKind, enumKind, traitKind, typeAliasKind, functionKind:
+			if uid, err := c.getDocfxUid(childId); err == nil {
+				name = xrefLink(c.getName(childId), uid)
+			}
+		case macroKind:
+		default:
+			continue
+		}
+		rows[kind] = append(rows[kind], fmt.Sprintf("| %s | %s |", name, docSummary(c.Index[childId].Docs)))
+	}
+	var tables []string
+	for _, group := range moduleSummaryGroups {
+		if len(rows[group.kind]) == 0 {
+			continue
+		}
+		header := []string{
+			fmt.Sprintf("**%s**", group.title),
+			"",
+			"| Name | Description |",
+			"| --- | --- |",
+		}
+		tables = append(tables, strings.Join(append(header, rows[group.kind]...), "\n"))
+	}
+	return strings.Join(tables, "\n\n")
+}
+
 func processStruct(c *crate, id string, page *docfxManagedReference, parent *docfxItem) error {
 	if c.Index[id].Inner.Struct != nil {
 		isNonExhaustive := isNonExhaustive(c.Index[id].Attrs)
 		for i := 0; i < len(c.Index[id].Inner.Struct.Kind.Plain.Fields); i++ {
 			fieldId := idToString(c.Index[id].Inner.Struct.Kind.Plain.Fields[i])
-			field, err := newDocfxItemFromField(c, parent, fieldId)
+			field, err := newDocfxItemFromField(c, page, parent, fieldId)
 			if err != nil {
 				return fmt.Errorf("error processing struct item with id %s: %w", id, err)
 			}
@@ -183,6 +339,7 @@ func processStruct(c *crate, id string, page *docfxManagedReference, parent *doc
 			} else {
 				field.Type = "field"
 			}
+			c.addSerdeNotes(field, fieldId, id, false)
 			page.appendItem(field)
 
 			reference, err := newDocfxReferenceFromDocfxItem(field, parent)
@@ -201,6 +358,14 @@ func processStruct(c *crate, id string, page *docfxManagedReference, parent *doc
 				return fmt.Errorf("error processing struct item with id %s: %w", id, err)
 			}
 		}
+
+		if c.isGapic() && c.isRequestBuilder(id) {
+			parent.appendRemarks(c.helpersCallout(parent.Uid, id))
+			collapseBuilderSetters(c, id, parent)
+		}
+		if c.isGapic() && c.isInGapicModule(id, gapicClientModule) {
+			parent.appendRemarks(c.rpcTable(parent.Uid, id))
+		}
 	}
 	return nil
 }
@@ -215,11 +380,12 @@ func processTypeAlias(c *crate, id string, page *docfxManagedReference, parent *
 			return fmt.Errorf("error processing type alias item with id %s: %w", id, err)
 		}
 		typeAliasString := fmt.Sprintf("pub type %s = %s;", lhsIdentifier, rhs)
-		comments, err := c.getDocString(id)
+		comments, err := c.getDocString(page.docs, id)
 		if err != nil {
 			return err
 		}
-		parent.Summary = fmt.Sprintf("```rust\n%s\n```\n\n%s", typeAliasString, comments)
+		parent.setDocString(comments)
+		parent.prependSummary(fmt.Sprintf("```rust\n%s\n```", typeAliasString))
 	}
 	return nil
 }
@@ -239,7 +405,7 @@ func processEnum(c *crate, id string, page *docfxManagedReference, parent *docfx
 	for i := 0; i < len(c.Index[id].Inner.Enum.Variants); i++ {
 		variantId := idToString(c.Index[id].Inner.Enum.Variants[i])
 
-		enumVariant, err := newDocfxItemFromEnumVariant(c, parent, variantId)
+		enumVariant, err := newDocfxItemFromEnumVariant(c, page, parent, variantId)
 		if err != nil {
 			return fmt.Errorf("error processing enum item with id %s: %w", id, err)
 		}
@@ -248,6 +414,7 @@ func processEnum(c *crate, id string, page *docfxManagedReference, parent *docfx
 		} else {
 			enumVariant.Type = "enumvariant"
 		}
+		c.addSerdeNotes(enumVariant, variantId, id, true)
 		page.appendItem(enumVariant)
 
 		reference, err := newDocfxReferenceFromDocfxItem(enumVariant, parent)
@@ -290,11 +457,14 @@ func processImplementation(c *crate, id string, page *docfxManagedReference, par
 		innerImplItemKind := c.getKind(innerImplItemId)
 		switch innerImplItemKind {
 		case functionKind:
-			function, err := newDocfxItemFromFunction(c, parent, innerImplItemId)
+			function, err := newDocfxItemFromFunction(c, page, parent, innerImplItemId)
 			if err != nil {
 				return fmt.Errorf("error processing item with id %s: %w", id, err)
 			}
 			function.Type = "implementation"
+			if rpc, ok := c.getGapicRpc(innerImplItemId); ok {
+				function.appendRemarks(c.rpcDetails(rpc))
+			}
 			page.appendItem(function)
 
 			reference, err := newDocfxReferenceFromDocfxItem(function, parent)
@@ -313,7 +483,7 @@ func processImplementation(c *crate, id string, page *docfxManagedReference, par
 	return nil
 }
 
-func newDocfxItemFromFunction(c *crate, parent *docfxItem, id string) (*docfxItem, error) {
+func newDocfxItemFromFunction(c *crate, page *docfxManagedReference, parent *docfxItem, id string) (*docfxItem, error) {
 	r := new(docfxItem)
 	r.Name = c.getName(id)
 	r.Uid = c.getDocfxUidWithParentPrefix(parent.Uid, id)
@@ -324,36 +494,37 @@ func newDocfxItemFromFunction(c *crate, parent *docfxItem, id string) (*docfxIte
 	}
 
 	// Type is explicitly not set as this function is used for multiple doc pipeline types.
-	comments, err := c.getDocString(id)
+	comments, err := c.getDocString(page.docs, id)
 	if err != nil {
 		return nil, err
 	}
-	r.Summary = fmt.Sprintf("```rust\n%s\n```\n\n%s", functionSignature, comments)
+	r.setDocString(comments)
+	r.prependSummary(fmt.Sprintf("```rust\n%s\n```", functionSignature))
 	return r, nil
 }
 
-func newDocfxItemFromEnumVariant(c *crate, parent *docfxItem, id string) (*docfxItem, error) {
+func newDocfxItemFromEnumVariant(c *crate, page *docfxManagedReference, parent *docfxItem, id string) (*docfxItem, error) {
 	r := new(docfxItem)
 	r.Name = c.getName(id)
 	r.Uid = c.getDocfxUidWithParentPrefix(parent.Uid, id)
-	comments, err := c.getDocString(id)
+	comments, err := c.getDocString(page.docs, id)
 	if err != nil {
 		return nil, err
 	}
-	r.Summary = comments
+	r.setDocString(comments)
 	return r, nil
 }
 
-func newDocfxItemFromField(c *crate, parent *docfxItem, id string) (*docfxItem, error) {
+func newDocfxItemFromField(c *crate, page *docfxManagedReference, parent *docfxItem, id string) (*docfxItem, error) {
 	r := new(docfxItem)
 	// TODO: Add the field type to Name.
 	r.Name = c.getName(id)
 	r.Uid = c.getDocfxUidWithParentPrefix(parent.Uid, id)
-	comments, err := c.getDocString(id)
+	comments, err := c.getDocString(page.docs, id)
 	if err != nil {
 		return nil, err
 	}
-	r.Summary = comments
+	r.setDocString(comments)
 	return r, nil
 }
 
@@ -385,8 +556,9 @@ type docfxReference struct {
 
 func newDocfxManagedReference(c *crate, id string) (*docfxManagedReference, error) {
 	r := new(docfxManagedReference)
+	r.docs = c.newDocContext(id)
 
-	parent, err := newDocfxItem(c, id)
+	parent, err := newDocfxItem(c, r, id)
 	if err != nil {
 		return nil, fmt.Errorf("error constructing page for %s: %w", id, err)
 	}
@@ -402,7 +574,11 @@ func newDocfxManagedReference(c *crate, id string) (*docfxManagedReference, erro
 	case traitKind:
 		err = processTrait(c, id, r, parent)
 	case crateKind:
-		fallthrough
+		// The summary is shown in the TOC and search results, keep it to
+		// the first paragraph of the crate docs.
+		parent.prependRemarks(c.Metadata.releaseBadge())
+		parent.appendRemarks(c.Metadata.links())
+		err = processModule(c, id, r, parent)
 	case moduleKind:
 		err = processModule(c, id, r, parent)
 	case structKind:
@@ -428,8 +604,14 @@ func generate(c *crate, outDir string) error {
 	if err := renderMetadata(c, outDir); err != nil {
 		errs = append(errs, err)
 	}
+	if err := renderOverview(c, outDir); err != nil {
+		errs = append(errs, err)
+	}
 
-	for id := range c.Index {
+	// Collect the ids of the items with a page, the pages are rendered
+	// concurrently below. Sorting the ids makes the errors deterministic.
+	var pages []string
+	for _, id := range slices.Sorted(maps.Keys(c.Index)) {
 		kind := c.getKind(id)
 		switch kind {
 		case crateKind:
@@ -443,10 +625,7 @@ func generate(c *crate, outDir string) error {
 		case typeAliasKind:
 			fallthrough
 		case moduleKind:
-			if err := renderReference(c, id, outDir); err != nil {
-				errs = append(errs, err)
-				continue
-			}
+			pages = append(pages, id)
 		case functionKind:
 			fallthrough
 		case structFieldKind:
@@ -459,6 +638,8 @@ func generate(c *crate, outDir string) error {
 			fallthrough
 		case assocConstKind:
 			fallthrough
+		case macroKind:
+			fallthrough
 		case strippedModuleKind:
 			fallthrough
 		case implKind:
@@ -471,6 +652,10 @@ func generate(c *crate, outDir string) error {
 		}
 	}
 
+	errs = append(errs, parallelFor(len(pages), c.Jobs, func(i int) error {
+		return renderReference(c, pages[i], outDir)
+	})...)
+
 	if toc, err := computeTOC(c); err == nil {
 		if err := renderTOC(toc, outDir); err != nil {
 			errs = append(errs, err)
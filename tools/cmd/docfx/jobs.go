@@ -0,0 +1,49 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "sync"
+
+// parallelFor calls `f(i)` for each `i` in `[0, n)`, running at most `jobs`
+// calls concurrently. A `jobs` value less than 1 runs the calls sequentially.
+//
+// Returns the errors in index order, so the result does not depend on the
+// scheduling of the goroutines.
+func parallelFor(n, jobs int, f func(i int) error) []error {
+	results := make([]error, n)
+	jobs = max(1, min(jobs, n))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range jobs {
+		wg.Go(func() {
+			for i := range indexes {
+				results[i] = f(i)
+			}
+		})
+	}
+	for i := range n {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var errs []error
+	for _, err := range results {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
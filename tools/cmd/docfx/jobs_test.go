@@ -0,0 +1,76 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParallelFor(t *testing.T) {
+	for _, jobs := range []int{0, 1, 3, 100} {
+		var calls atomic.Int32
+		got := parallelFor(10, jobs, func(i int) error {
+			calls.Add(1)
+			if i%3 == 0 {
+				return fmt.Errorf("error %d", i)
+			}
+			return nil
+		})
+		if calls.Load() != 10 {
+			t.Errorf("expected 10 calls with jobs=%d, got=%d", jobs, calls.Load())
+		}
+		var messages []string
+		for _, err := range got {
+			messages = append(messages, err.Error())
+		}
+		want := []string{"error 0", "error 3", "error 6", "error 9"}
+		if diff := cmp.Diff(want, messages); diff != "" {
+			t.Errorf("mismatch with jobs=%d (-want, +got):\n%s", jobs, diff)
+		}
+	}
+}
+
+func TestParallelForBounded(t *testing.T) {
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+	done := make(chan []error)
+	go func() {
+		done <- parallelFor(6, 2, func(i int) error {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			<-release
+			running.Add(-1)
+			return nil
+		})
+	}()
+	for range 6 {
+		release <- struct{}{}
+	}
+	if errs := <-done; len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	if got := peak.Load(); got > 2 {
+		t.Errorf("expected at most 2 concurrent calls, got=%d", got)
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	fspath "path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+)
+
+// linkRewrites maps the links in a docstring to their URL in the generated
+// pages.
+type linkRewrites struct {
+	destinations map[string]string
+	replacer     *strings.Replacer
+}
+
+// Replace rewrites the links in a line of markdown.
+func (r *linkRewrites) Replace(line string) string {
+	if r == nil {
+		return line
+	}
+	return r.replacer.Replace(line)
+}
+
+func (r *linkRewrites) destination(d string) string {
+	if r == nil {
+		return d
+	}
+	if rewrite, ok := r.destinations[d]; ok {
+		return rewrite
+	}
+	return d
+}
+
+// rewriteLinks finds the links in a docstring that need to change in the
+// generated pages:
+//   - links to headings with a renamed anchor, as found in `fragments`.
+//   - links and images referencing local files, see `resolveAsset()`.
+//   - links to rustdoc HTML pages, see `resolveRustdocLink()`.
+//
+// Returns nil if `ctx` is nil, as there is no page to resolve the links.
+func (ctx *docContext) rewriteLinks(doc ast.Node, pc parser.Context, contents string, fragments map[string]string) (*linkRewrites, error) {
+	if ctx == nil {
+		return nil, nil
+	}
+	type link struct {
+		destination string
+		isImage     bool
+	}
+	var links []link
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := node.(type) {
+		case *ast.Link:
+			links = append(links, link{string(n.Destination), false})
+		case *ast.Image:
+			links = append(links, link{string(n.Destination), true})
+		}
+		return ast.WalkContinue, nil
+	})
+	for _, r := range pc.References() {
+		links = append(links, link{string(r.Destination()), false})
+	}
+	for _, m := range htmlImageSource.FindAllStringSubmatch(contents, -1) {
+		links = append(links, link{m[1], true})
+	}
+
+	rewrites := &linkRewrites{destinations: map[string]string{}}
+	var errs []error
+	var pairs []string
+	for _, l := range links {
+		if _, ok := rewrites.destinations[l.destination]; ok {
+			continue
+		}
+		var target string
+		switch {
+		case fragments[l.destination] != "":
+			target = fragments[l.destination]
+		case isRustdocLink(l.destination):
+			uid, ok := ctx.resolveRustdocLink(l.destination)
+			if !ok {
+				ctx.crate.log().Warn("cannot resolve rustdoc link", "link", l.destination, "page", ctx.pageUid())
+				continue
+			}
+			target = "xref:" + uid
+		case isLocalAsset(l.destination, l.isImage):
+			t, err := ctx.resolveAsset(l.destination)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if t == "" {
+				continue
+			}
+			target = t
+		default:
+			continue
+		}
+		rewrites.destinations[l.destination] = target
+		// Match the end of the destination, a destination may be a prefix
+		// of another, e.g. `#examples` and `#examples-1`, or
+		// `struct.Key.html` and `struct.Key.html#structfield.id`.
+		pairs = append(pairs,
+			"]("+l.destination+")", "]("+target+")",
+			"]("+l.destination+" ", "]("+target+" ",
+			`src="`+l.destination+`"`, `src="`+target+`"`,
+			`src='`+l.destination+`'`, `src='`+target+`'`)
+	}
+	if len(errs) != 0 {
+		return nil, errors.Join(errs...)
+	}
+	rewrites.replacer = strings.NewReplacer(pairs...)
+	return rewrites, nil
+}
+
+var (
+	// Matches rustdoc pages, such as `struct.Client.html` or `index.html`.
+	rustdocPage = regexp.MustCompile(`^(?:([a-z]+)\.([A-Za-z0-9_]+)|index)\.html$`)
+	// Matches anchors for members in rustdoc pages, such as `#method.send`.
+	rustdocAnchor = regexp.MustCompile(`^(method|tymethod|variant|structfield|associatedtype|associatedconstant)\.([A-Za-z0-9_]+)$`)
+)
+
+// rustdocPathKinds maps the kinds used in rustdoc page names to the kinds in
+// the rustdoc JSON `paths`.
+var rustdocPathKinds = map[string]string{
+	"struct":     "struct",
+	"enum":       "enum",
+	"trait":      "trait",
+	"type":       "type_alias",
+	"fn":         "function",
+	"macro":      "macro",
+	"constant":   "constant",
+	"static":     "static",
+	"union":      "union",
+	"traitalias": "trait_alias",
+}
+
+// isRustdocLink returns true if `destination` is a relative link to a rustdoc
+// HTML page, or a member anchor in the current page.
+func isRustdocLink(destination string) bool {
+	if strings.Contains(destination, "://") {
+		return false
+	}
+	page, anchor, _ := strings.Cut(destination, "#")
+	if page == "" {
+		return rustdocAnchor.MatchString(anchor)
+	}
+	return rustdocPage.MatchString(fspath.Base(page))
+}
+
+// pageUid returns the uid of the page, or the empty string if unknown.
+func (ctx *docContext) pageUid() string {
+	uid, err := ctx.crate.getDocfxUid(ctx.pageId)
+	if err != nil {
+		return ""
+	}
+	return uid
+}
+
+// resolveRustdocLink returns the docfx uid for a link to a rustdoc HTML page.
+//
+// The links are relative to the page of the current item. In rustdoc, the
+// page for `crate::model::Secret` is `crate/model/struct.Secret.html`. The
+// page for a module, such as `crate::model`, is `crate/model/index.html`.
+func (ctx *docContext) resolveRustdocLink(destination string) (string, bool) {
+	c := ctx.crate
+	page, anchor, _ := strings.Cut(destination, "#")
+	uid := ctx.pageUid()
+	if page != "" {
+		summary, ok := c.Paths[ctx.pageId]
+		if !ok {
+			return "", false
+		}
+		dir := summary.Path
+		if kind := c.getKind(ctx.pageId); kind != moduleKind && kind != crateKind {
+			dir = dir[:len(dir)-1]
+		}
+		segments := slices.Clone(dir)
+		parts := strings.Split(page, "/")
+		for _, p := range parts[:len(parts)-1] {
+			switch p {
+			case ".", "":
+			case "..":
+				if len(segments) == 0 {
+					return "", false
+				}
+				segments = segments[:len(segments)-1]
+			default:
+				segments = append(segments, p)
+			}
+		}
+		m := rustdocPage.FindStringSubmatch(parts[len(parts)-1])
+		kind := "module"
+		if m[1] != "" {
+			kind = rustdocPathKinds[m[1]]
+			segments = append(segments, m[2])
+		}
+		id, ok := c.findLocalPath(kind, segments)
+		if !ok {
+			return "", false
+		}
+		target, err := c.getDocfxUid(id)
+		if err != nil {
+			return "", false
+		}
+		uid = target
+	}
+	if uid == "" {
+		return "", false
+	}
+	m := rustdocAnchor.FindStringSubmatch(anchor)
+	if m == nil {
+		// Other anchors, such as `#implementations`, link to the page.
+		return uid, true
+	}
+	return fmt.Sprintf("%s.%s", uid, m[2]), true
+}
+
+// findLocalPath returns the id of the item in the crate with the given kind
+// and path.
+func (c *crate) findLocalPath(kind string, path []string) (string, bool) {
+	for id, summary := range c.Paths {
+		if summary.CrateId == 0 && summary.Kind == kind && slices.Equal(summary.Path, path) {
+			return id, true
+		}
+	}
+	return "", false
+}
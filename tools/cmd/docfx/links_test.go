@@ -0,0 +1,104 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIsRustdocLink(t *testing.T) {
+	for _, test := range []struct {
+		destination string
+		want        bool
+	}{
+		{"struct.Client.html", true},
+		{"../model/enum.State.html", true},
+		{"../model/index.html", true},
+		{"struct.Client.html#method.send", true},
+		{"#method.send", true},
+		{"#variant.Active", true},
+		{"#examples", false},
+		{"https://docs.rs/google-cloud-gax/latest/google_cloud_gax/struct.Options.html", false},
+		{"crate::model::Secret", false},
+		{"README.md", false},
+	} {
+		if got := isRustdocLink(test.destination); got != test.want {
+			t.Errorf("isRustdocLink(%q) = %v, want = %v", test.destination, got, test.want)
+		}
+	}
+}
+
+func TestResolveRustdocLinks(t *testing.T) {
+	input, err := testDataPublicCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pageUid := "struct.google_cloud_security_publicca_v1.client.PublicCertificateAuthorityService"
+	ctx := input.newDocContext(findIdByUid(t, input, pageUid))
+	for _, test := range []struct {
+		destination string
+		want        string
+	}{
+		{"#method.create_external_account_key", pageUid + ".create_external_account_key"},
+		{"struct.PublicCertificateAuthorityService.html", pageUid},
+		{"../model/struct.ExternalAccountKey.html", "struct.google_cloud_security_publicca_v1.model.ExternalAccountKey"},
+		{"../model/struct.ExternalAccountKey.html#structfield.key_id", "struct.google_cloud_security_publicca_v1.model.ExternalAccountKey.key_id"},
+		{"../builder/public_certificate_authority_service/index.html", "module.google_cloud_security_publicca_v1.builder.public_certificate_authority_service"},
+		{"../index.html", "crate.google_cloud_security_publicca_v1"},
+		{"./index.html#structs", "module.google_cloud_security_publicca_v1.client"},
+	} {
+		got, ok := ctx.resolveRustdocLink(test.destination)
+		if !ok {
+			t.Errorf("cannot resolve %q", test.destination)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("resolveRustdocLink(%q) = %q, want = %q", test.destination, got, test.want)
+		}
+	}
+	for _, destination := range []string{"struct.Missing.html", "../../../index.html"} {
+		if got, ok := ctx.resolveRustdocLink(destination); ok {
+			t.Errorf("expected an error resolving %q, got = %q", destination, got)
+		}
+	}
+
+	for _, test := range []struct {
+		docs string
+		want string
+	}{
+		{
+			"See [the key](../model/struct.ExternalAccountKey.html) and [Missing](struct.Missing.html).",
+			"See [the key](xref:struct.google_cloud_security_publicca_v1.model.ExternalAccountKey) and [Missing](struct.Missing.html).",
+		},
+		{
+			"See [a](../model/struct.ExternalAccountKey.html) and [b](../model/struct.ExternalAccountKey.html#structfield.key_id).",
+			"See [a](xref:struct.google_cloud_security_publicca_v1.model.ExternalAccountKey) and [b](xref:struct.google_cloud_security_publicca_v1.model.ExternalAccountKey.key_id).",
+		},
+		{
+			`See [a](../model/struct.ExternalAccountKey.html "the key").`,
+			`See [a](xref:struct.google_cloud_security_publicca_v1.model.ExternalAccountKey "the key").`,
+		},
+	} {
+		got, err := processDocStringWithContext(test.docs, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("mismatch in processDocStringWithContext for rustdoc links (-want, +got)\n:%s", diff)
+		}
+	}
+}
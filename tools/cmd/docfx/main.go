@@ -26,6 +26,50 @@ The flags are:
 		Write the result custom/file/path instead of stdout.
 	    -project-root
 		Top level directory of googleapis/google-cloud-rust.
+	    -heading-base
+		The level for the top-level headings in docstrings (default 4).
+	    -toolchain
+		The nightly toolchain used to build the rustdoc JSON (default
+		nightly). Pin a dated nightly, e.g. nightly-2025-09-26, to get a
+		supported rustdoc JSON format version.
+	    -batch-size
+		The number of crates documented by each `cargo doc` invocation. Use
+		0 to document all the crates in a single invocation (default 1,
+		one `cargo rustdoc` invocation per crate).
+	    -jobs
+		The number of crates processed concurrently once their rustdoc JSON
+		is ready, and the number of pages rendered concurrently within each
+		crate (default 1).
+	    -cache
+		The cache manifest recording the inputs of each generated crate.
+		Crates with unchanged inputs are not regenerated nor uploaded
+		(default .rustdocfx-cache.json in the output directory).
+	    -force
+		Regenerate and upload all the crates, ignoring the cache manifest.
+	    -since
+		Only generate the crates containing files changed since this git
+		reference, e.g. a commit or branch.
+	    -reverse-deps
+		With -since, also generate the workspace crates that depend,
+		directly or indirectly, on the changed crates. Requires the
+		cargo-metadata discovery, or the output of `cargo metadata` in
+		-workspace.
+	    -config
+		The configuration file, with the crates to include and exclude, and
+		per-crate overrides (default rustdocfx.yaml in the project root,
+		if it exists).
+	    -rustdoc-dir
+		Read pre-built rustdoc JSON files from this directory. Requires
+		-workspace.
+	    -discovery
+		The command to list the workspace crates: cargo-metadata (the
+		default) or cargo-workspaces.
+	    -workspace
+		Read the workspace crates from this file, with the output of
+		`cargo workspaces plan --json` or `cargo metadata`.
+
+With both -rustdoc-dir and -workspace the generator runs offline, without
+invoking cargo.
 */
 package main
 
@@ -36,86 +80,32 @@ import (
 	"log"
 	"os"
 	"os/exec"
-	"path/filepath"
-	"slices"
 	"strings"
 )
 
-// crateDenyList enumerates the crates which do not get documents at cloud.google.com
-var crateDenyList = []string{"google-cloud-gax-internal"}
-
 func main() {
-	out := flag.String("out", "docfx", "Output directory within project-root (default docfx)")
-	projectRoot := flag.String("project-root", "", "Top level directory of googleapis/google-cloud-rust")
-	upload := flag.String("staging-bucket", "", "Upload the generated docfx to the gcs bucket using docuploader")
+	opts := &options{}
+	flag.StringVar(&opts.Out, "out", "docfx", "Output directory within project-root (default docfx)")
+	flag.StringVar(&opts.ProjectRoot, "project-root", "", "Top level directory of googleapis/google-cloud-rust")
+	flag.StringVar(&opts.Upload, "staging-bucket", "", "Upload the generated docfx to the gcs bucket using docuploader")
+	flag.IntVar(&opts.HeadingBase, "heading-base", defaultHeadingBase, "The level for the top-level headings in docstrings")
+	flag.StringVar(&opts.Toolchain, "toolchain", "nightly", "The nightly toolchain used to build the rustdoc JSON, e.g. nightly-2025-09-26")
+	flag.IntVar(&opts.BatchSize, "batch-size", 1, "The number of crates documented by each cargo doc invocation, 0 for all the crates")
+	flag.IntVar(&opts.Jobs, "jobs", 1, "The number of crates, and pages within each crate, processed concurrently")
+	flag.StringVar(&opts.Cache, "cache", "", "The cache manifest, defaults to .rustdocfx-cache.json in the output directory")
+	flag.BoolVar(&opts.Force, "force", false, "Regenerate and upload all the crates, even if their inputs are unchanged")
+	flag.StringVar(&opts.Since, "since", "", "Only generate the crates with files changed since this git reference")
+	flag.BoolVar(&opts.ReverseDependencies, "reverse-deps", false, "With -since, also generate the crates depending on the changed crates")
+	flag.StringVar(&opts.Config, "config", "", "The configuration file, defaults to rustdocfx.yaml in the project root")
+	flag.StringVar(&opts.RustdocDir, "rustdoc-dir", "", "Read pre-built rustdoc JSON files from this directory instead of running cargo rustdoc")
+	flag.StringVar(&opts.Discovery, "discovery", discoveryCargoMetadata, "The command to list the workspace crates, cargo-metadata or cargo-workspaces")
+	flag.StringVar(&opts.Workspace, "workspace", "", "Read the workspace crates from this file, the output of `cargo workspaces plan --json` or `cargo metadata`")
 	flag.Parse()
+	opts.Crates = flag.Args()
 
-	crates := flag.Args()
-
-	if err := preFlightTests(*upload); err != nil {
-		log.Fatal(err)
-	}
-
-	// Create a temporary file to store `cargo workspace plan` output.
-	tempFile, err := os.CreateTemp("", "cargo-plan-")
-	if err != nil {
-		log.Fatalf("Unable to create temp file for cargo workspace plan: %v\n", err)
-	}
-	defer func() { _ = os.Remove(tempFile.Name()) }()
-	fmt.Printf("Created tmp file %s for cargo workspace plan\n", tempFile.Name())
-
-	if err := runCmd(tempFile, *projectRoot, "cargo", "workspaces", "plan", "--json"); err != nil {
-		log.Fatalf("Unable to get package list: %v", err)
-	}
-	fmt.Printf("using cargo workspace plan for crates\n")
-
-	byteValue, err := os.ReadFile(tempFile.Name())
-	if err != nil {
-		log.Fatalf("Unable to read cargo workspace json file: %v\n", err)
-	}
-
-	workspaceCrates, err := getWorkspaceCrates(byteValue)
-	if err != nil {
-		log.Fatalf("Error getting workspace crates: %v\n", err)
-	}
-
-	if err := renderIndex(workspaceCrates, filepath.Join(*projectRoot, *out)); err != nil {
+	if err := run(opts); err != nil {
 		log.Fatal(err)
 	}
-
-	for i, crate := range workspaceCrates {
-		// TODO: Allow for regex on crate names instead.
-		if !slices.Contains(crateDenyList, crate.Name) && (len(crates) == 0 || slices.Contains(crates, crate.Name)) {
-			if err := runCmd(nil, *projectRoot, "cargo", "+nightly", "-Z", "unstable-options", "rustdoc", "--output-format=json", "--package", crate.Name); err != nil {
-				fmt.Printf("Error in cargo rustdoc command: %v", err)
-				continue
-			}
-			// cargo names are snake case while cargo rustdoc output files are kebab case.
-			fileName := fmt.Sprintf("%s.json", strings.ReplaceAll(workspaceCrates[i].Name, "-", "_"))
-			file := filepath.Join(*projectRoot, "/target/doc", fileName)
-			jsonBytes, err := os.ReadFile(file)
-			if err != nil {
-				log.Fatalf("Error reading rustdoc file: %v\n", err)
-			}
-			unmarshalRustdoc(&crate, jsonBytes)
-
-			crateOutDir := filepath.Join(*projectRoot, *out, crate.Name)
-			_ = os.MkdirAll(crateOutDir, 0777) // Ignore errors
-
-			err = generate(&crate, crateOutDir)
-			if err != nil {
-				log.Fatalf("failed to generate for crate %s: %v\n", crate.Name, err)
-			}
-			fmt.Printf("Generated docfx for crate: %s\n", crate.Name)
-
-			if *upload != "" {
-				fmt.Printf("Uploading crate: %s\n", crate.Name)
-				if err := runCmd(nil, "", "docuploader", "upload", fmt.Sprintf("--staging-bucket=%s", *upload), "--destination-prefix=docfx", fmt.Sprintf("--metadata-file=%s/docs.metadata", crateOutDir), crateOutDir); err != nil {
-					fmt.Printf("error uploading files: %v\n", err)
-				}
-			}
-		}
-	}
 }
 
 func runCmd(stdout io.Writer, dir, name string, args ...string) error {
@@ -137,3 +127,18 @@ func runCmd(stdout io.Writer, dir, name string, args ...string) error {
 	}
 	return nil
 }
+
+// runCmdWithLog runs a command, writing a description of the command and all
+// its output to `log`.
+func runCmdWithLog(log io.Writer, dir, name string, args ...string) error {
+	fmt.Fprintf(log, "Running command: dir=%s, name=%s, args=%s\n", dir, name, strings.Join(args, " "))
+
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Stdout = log
+	cmd.Stderr = log
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("cmd.Run: %w", err)
+	}
+	return nil
+}
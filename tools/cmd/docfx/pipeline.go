@@ -0,0 +1,313 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// options configures a run of the generator, see `main()` for the flags.
+type options struct {
+	// Out is the output directory, relative to ProjectRoot.
+	Out         string
+	ProjectRoot string
+	// Upload is the staging bucket for docuploader, empty to skip uploads.
+	Upload      string
+	HeadingBase int
+	// RustdocDir contains pre-built rustdoc JSON files. If set, the
+	// generator does not run `cargo rustdoc`.
+	RustdocDir string
+	// Workspace is a file with the output of `cargo workspaces plan --json`
+	// or `cargo metadata`. If set, the generator does not run any discovery
+	// command.
+	Workspace string
+	// Discovery selects the command used to list the workspace crates, one
+	// of `discoveryCargoMetadata` or `discoveryCargoWorkspaces`.
+	Discovery string
+	// Toolchain is the nightly toolchain used to build the rustdoc JSON, e.g.
+	// `nightly` or `nightly-2025-08-01`.
+	Toolchain string
+	// BatchSize is the number of crates documented by each `cargo doc`
+	// invocation. With 1, each crate runs its own `cargo rustdoc`. With 0,
+	// all the crates are documented in a single `cargo doc` invocation.
+	BatchSize int
+	// Jobs is the number of crates processed concurrently, and the number of
+	// pages rendered concurrently for each crate.
+	Jobs int
+	// Cache is the path of the cache manifest. Uses `defaultCacheFile` in the
+	// output directory if empty.
+	Cache string
+	// Force regenerates and uploads all the crates, even if their inputs are
+	// unchanged since the last run.
+	Force bool
+	// Since restricts the generation to the crates with files changed since
+	// this git reference, if set.
+	Since string
+	// ReverseDependencies extends the crates selected by `Since` with the
+	// crates that depend on them.
+	ReverseDependencies bool
+	// Config is the configuration file. Uses `defaultConfigFile` in the
+	// project root, if it exists, when empty.
+	Config string
+	// Crates restricts the generation to these crates, all the crates if
+	// empty.
+	Crates []string
+}
+
+// offline returns true if the generator runs without invoking cargo.
+func (o *options) offline() bool {
+	return o.RustdocDir != "" && o.Workspace != ""
+}
+
+// run executes the full pipeline: discover the workspace crates, build their
+// rustdoc JSON, generate the DocFX YAML and optionally upload the results.
+func run(opts *options) error {
+	if opts.HeadingBase < 1 || opts.HeadingBase > 6 {
+		return fmt.Errorf("invalid -heading-base %d, must be between 1 and 6", opts.HeadingBase)
+	}
+	if opts.Discovery != discoveryCargoMetadata && opts.Discovery != discoveryCargoWorkspaces {
+		return fmt.Errorf("invalid -discovery %q, must be %q or %q", opts.Discovery, discoveryCargoMetadata, discoveryCargoWorkspaces)
+	}
+	if !strings.HasPrefix(opts.Toolchain, "nightly") {
+		return fmt.Errorf("invalid -toolchain %q, rustdoc JSON requires a nightly toolchain", opts.Toolchain)
+	}
+	if opts.Jobs < 1 {
+		return fmt.Errorf("invalid -jobs %d, must be positive", opts.Jobs)
+	}
+	if opts.BatchSize < 0 {
+		return fmt.Errorf("invalid -batch-size %d, must be zero or positive", opts.BatchSize)
+	}
+	if opts.ReverseDependencies && opts.Since == "" {
+		return fmt.Errorf("-reverse-deps requires -since")
+	}
+	if opts.ReverseDependencies && opts.Workspace == "" && opts.Discovery != discoveryCargoMetadata {
+		return fmt.Errorf("-reverse-deps requires the %q discovery", discoveryCargoMetadata)
+	}
+	if opts.RustdocDir != "" && opts.Workspace == "" {
+		return fmt.Errorf("-rustdoc-dir requires -workspace")
+	}
+	cfg, err := loadConfig(opts.Config, opts.ProjectRoot)
+	if err != nil {
+		return err
+	}
+	if err := preFlightTests(opts); err != nil {
+		return err
+	}
+
+	workspaceCrates, err := loadWorkspace(opts)
+	if err != nil {
+		return fmt.Errorf("error getting workspace crates: %w", err)
+	}
+
+	outDir := filepath.Join(opts.ProjectRoot, opts.Out)
+	if err := renderIndex(workspaceCrates, outDir); err != nil {
+		return err
+	}
+
+	cachePath := opts.Cache
+	if cachePath == "" {
+		cachePath = filepath.Join(outDir, defaultCacheFile)
+	}
+	cache, err := loadCache(cachePath)
+	if err != nil {
+		return err
+	}
+
+	var changed []string
+	if opts.Since != "" {
+		files, err := changedFiles(opts)
+		if err != nil {
+			return err
+		}
+		if changed, err = changedCrates(workspaceCrates, files, opts.ReverseDependencies); err != nil {
+			return err
+		}
+		fmt.Printf("crates changed since %s: %s\n", opts.Since, strings.Join(changed, ", "))
+	}
+
+	var selected []*crate
+	for i := range workspaceCrates {
+		crate := &workspaceCrates[i]
+		ok, err := cfg.selected(crate.Name)
+		if err != nil {
+			return err
+		}
+		if !ok || (len(opts.Crates) != 0 && !slices.Contains(opts.Crates, crate.Name)) {
+			continue
+		}
+		if opts.Since != "" && !slices.Contains(changed, crate.Name) {
+			continue
+		}
+		if crate.Config, err = cfg.crateConfig(crate); err != nil {
+			return err
+		}
+		if crate.Config.Skip != "" {
+			fmt.Printf("Skipping crate %s: %s\n", crate.Name, crate.Config.Skip)
+			continue
+		}
+		selected = append(selected, crate)
+	}
+
+	// Each crate logs to its own buffer, printed when the crate completes,
+	// so the logs from different crates are not interleaved. The build logs
+	// are printed in the same way, once each batch completes.
+	var mu sync.Mutex
+	flush := func(log *bytes.Buffer) {
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = os.Stdout.Write(log.Bytes())
+	}
+
+	// Build the rustdoc JSON in the background, sending the position of each
+	// crate to the workers once its JSON is ready, or its build failed.
+	ready := make(chan buildResult)
+	go func() {
+		defer close(ready)
+		for _, batch := range batches(selected, opts.BatchSize) {
+			built, failed := batch, map[*crate]error{}
+			if opts.RustdocDir == "" {
+				var log bytes.Buffer
+				built, failed = buildRustdoc(opts, batch, &log)
+				flush(&log)
+			}
+			for _, crate := range batch {
+				if slices.Contains(built, crate) || failed[crate] != nil {
+					ready <- buildResult{index: slices.Index(selected, crate), err: failed[crate]}
+				}
+			}
+		}
+	}()
+
+	// The errors are reported in workspace order, independent of the
+	// scheduling.
+	errs := make([]error, len(selected))
+	var wg sync.WaitGroup
+	for range opts.Jobs {
+		wg.Go(func() {
+			for result := range ready {
+				i := result.index
+				if result.err != nil {
+					errs[i] = result.err
+					continue
+				}
+				var log bytes.Buffer
+				errs[i] = generateCrate(opts, cache, selected[i], outDir, &log)
+				flush(&log)
+			}
+		})
+	}
+	wg.Wait()
+	// Save the cache even if some crates failed, the successful crates are
+	// skipped in the next run.
+	if err := cache.save(cachePath); err != nil {
+		errs = append(errs, fmt.Errorf("error saving cache manifest: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// buildResult reports that the rustdoc JSON for the crate at `index` is
+// ready, or the error building it.
+type buildResult struct {
+	index int
+	err   error
+}
+
+// batches splits `crates` in groups of at most `size` crates. With a `size`
+// of zero, all the crates are in a single group.
+func batches(crates []*crate, size int) [][]*crate {
+	if len(crates) == 0 {
+		return nil
+	}
+	if size <= 0 {
+		return [][]*crate{crates}
+	}
+	return slices.Collect(slices.Chunk(crates, size))
+}
+
+// generateCrate loads the rustdoc JSON for `crate`, generates its DocFX YAML
+// and uploads the result, if requested. All the messages about the crate are
+// written to `log`.
+//
+// Crates with the same inputs as in the cache manifest are skipped, unless
+// `opts.Force` is set.
+func generateCrate(opts *options, cache *cacheManifest, crate *crate, outDir string, log io.Writer) error {
+	jsonBytes, err := os.ReadFile(rustdocFile(opts, crate.Name))
+	if err != nil {
+		return fmt.Errorf("error reading rustdoc file for crate %s: %w", crate.Name, err)
+	}
+	entry, err := newCacheEntry(opts, crate, jsonBytes)
+	if err != nil {
+		return fmt.Errorf("error computing cache entry for crate %s: %w", crate.Name, err)
+	}
+	if !opts.Force && cache.unchanged(crate.Name, entry, crate.Location) {
+		fmt.Fprintf(log, "Skipping unchanged crate: %s\n", crate.Name)
+		return nil
+	}
+	if err := unmarshalRustdoc(crate, jsonBytes); err != nil {
+		return fmt.Errorf("error reading rustdoc file for crate %s: %w", crate.Name, err)
+	}
+	if metadata, err := readRepoMetadata(crate.Location); err == nil {
+		crate.Metadata = metadata
+	}
+	if crate.WireNames, err = readWireNames(crate.Location); err != nil {
+		return fmt.Errorf("error reading the generated serializer for crate %s: %w", crate.Name, err)
+	}
+	crate.ProjectRoot = opts.ProjectRoot
+	crate.HeadingBase = opts.HeadingBase
+	crate.Jobs = opts.Jobs
+	crate.logger = slog.New(slog.NewTextHandler(log, nil)).With("crate", crate.Name)
+	crate.assets = &assetSources{}
+
+	crateOutDir := filepath.Join(outDir, crate.Name)
+	_ = os.MkdirAll(crateOutDir, 0777) // Ignore errors
+
+	if err := generate(crate, crateOutDir); err != nil {
+		return fmt.Errorf("failed to generate for crate %s: %w", crate.Name, err)
+	}
+	fmt.Fprintf(log, "Generated docfx for crate: %s\n", crate.Name)
+	if entry.Assets, err = hashAssets(crate.Location, crate.assets.sorted()); err != nil {
+		return fmt.Errorf("error computing cache entry for crate %s: %w", crate.Name, err)
+	}
+
+	if opts.Upload != "" {
+		fmt.Fprintf(log, "Uploading crate: %s\n", crate.Name)
+		if err := runCmdWithLog(log, "", "docuploader", "upload", fmt.Sprintf("--staging-bucket=%s", opts.Upload), "--destination-prefix=docfx", fmt.Sprintf("--metadata-file=%s/docs.metadata", crateOutDir), crateOutDir); err != nil {
+			// Do not record the crate in the cache, so the next run retries
+			// the upload.
+			return fmt.Errorf("error uploading crate %s: %w", crate.Name, err)
+		}
+	}
+	cache.record(crate.Name, entry)
+	return nil
+}
+
+// rustdocFile returns the path of the rustdoc JSON file for `crateName`.
+func rustdocFile(opts *options, crateName string) string {
+	// cargo names are snake case while cargo rustdoc output files are kebab case.
+	fileName := fmt.Sprintf("%s.json", strings.ReplaceAll(crateName, "-", "_"))
+	if opts.RustdocDir != "" {
+		return filepath.Join(opts.RustdocDir, fileName)
+	}
+	return filepath.Join(opts.ProjectRoot, "target", "doc", fileName)
+}
@@ -0,0 +1,409 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRunOffline(t *testing.T) {
+	location, err := filepath.Abs("../../../src/generated/cloud/security/publicca/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	workspace, err := json.Marshal([]map[string]any{
+		{"name": "google-cloud-security-publicca-v1", "version": "1.0.0", "location": location},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	projectRoot := t.TempDir()
+	workspaceFile := filepath.Join(projectRoot, "plan.json")
+	if err := os.WriteFile(workspaceFile, workspace, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &options{
+		Out:         "docfx",
+		ProjectRoot: projectRoot,
+		HeadingBase: defaultHeadingBase,
+		Discovery:   discoveryCargoMetadata,
+		Jobs:        1,
+		Toolchain:   "nightly",
+		RustdocDir:  "testdata",
+		Workspace:   workspaceFile,
+	}
+	if err := run(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	outDir := filepath.Join(projectRoot, "docfx")
+	for _, name := range []string{
+		"_libraries.json",
+		"google-cloud-security-publicca-v1/docs.metadata",
+		"google-cloud-security-publicca-v1/overview.md",
+		"google-cloud-security-publicca-v1/toc.yml",
+		"google-cloud-security-publicca-v1/crate.google_cloud_security_publicca_v1.yml",
+		"google-cloud-security-publicca-v1/struct.google_cloud_security_publicca_v1.client.PublicCertificateAuthorityService.yml",
+	} {
+		if _, err := os.Stat(filepath.Join(outDir, name)); err != nil {
+			t.Errorf("missing output file %s: %v", name, err)
+		}
+	}
+	contents, err := os.ReadFile(filepath.Join(outDir, "google-cloud-security-publicca-v1", "docs.metadata"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `version: "1.0.0"`; !strings.Contains(string(contents), want) {
+		t.Errorf("expected %q in docs.metadata: %s", want, contents)
+	}
+}
+
+func TestRunOfflineSkipsUnchanged(t *testing.T) {
+	location, err := filepath.Abs("../../../src/generated/cloud/security/publicca/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	workspace, err := json.Marshal([]map[string]any{
+		{"name": "google-cloud-security-publicca-v1", "version": "1.0.0", "location": location},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	projectRoot := t.TempDir()
+	workspaceFile := filepath.Join(projectRoot, "plan.json")
+	if err := os.WriteFile(workspaceFile, workspace, 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := &options{
+		Out:         "docfx",
+		ProjectRoot: projectRoot,
+		HeadingBase: defaultHeadingBase,
+		Discovery:   discoveryCargoMetadata,
+		Jobs:        1,
+		Toolchain:   "nightly",
+		RustdocDir:  "testdata",
+		Workspace:   workspaceFile,
+		Cache:       filepath.Join(projectRoot, "cache.json"),
+	}
+	if err := run(opts); err != nil {
+		t.Fatal(err)
+	}
+	toc := filepath.Join(projectRoot, "docfx", "google-cloud-security-publicca-v1", "toc.yml")
+	if err := os.Remove(toc); err != nil {
+		t.Fatal(err)
+	}
+
+	// The inputs are unchanged, the crate is skipped.
+	if err := run(opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(toc); err == nil {
+		t.Errorf("expected the unchanged crate to be skipped")
+	}
+
+	opts.Force = true
+	if err := run(opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(toc); err != nil {
+		t.Errorf("expected the crate to be regenerated with -force: %v", err)
+	}
+}
+
+func TestRunOfflineConfigSkip(t *testing.T) {
+	location, err := filepath.Abs("../../../src/generated/cloud/security/publicca/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	workspace, err := json.Marshal([]map[string]any{
+		{"name": "google-cloud-security-publicca-v1", "version": "1.0.0", "location": location},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	projectRoot := t.TempDir()
+	workspaceFile := filepath.Join(projectRoot, "plan.json")
+	if err := os.WriteFile(workspaceFile, workspace, 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := "crates:\n  google-cloud-security-publicca-v1:\n    skip: testing\n"
+	if err := os.WriteFile(filepath.Join(projectRoot, defaultConfigFile), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := &options{
+		Out:         "docfx",
+		ProjectRoot: projectRoot,
+		HeadingBase: defaultHeadingBase,
+		Discovery:   discoveryCargoMetadata,
+		Jobs:        1,
+		Toolchain:   "nightly",
+		RustdocDir:  "testdata",
+		Workspace:   workspaceFile,
+	}
+	if err := run(opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(projectRoot, "docfx", "google-cloud-security-publicca-v1")); err == nil {
+		t.Errorf("expected the skipped crate to have no output")
+	}
+}
+
+func TestRunOfflineRequiresWorkspace(t *testing.T) {
+	opts := &options{
+		Out:         "docfx",
+		ProjectRoot: t.TempDir(),
+		HeadingBase: defaultHeadingBase,
+		Discovery:   discoveryCargoMetadata,
+		Jobs:        1,
+		Toolchain:   "nightly",
+		RustdocDir:  "testdata",
+	}
+	if err := run(opts); err == nil {
+		t.Errorf("expected an error without -workspace")
+	}
+}
+
+func TestRunInvalidDiscovery(t *testing.T) {
+	opts := &options{
+		Out:         "docfx",
+		ProjectRoot: t.TempDir(),
+		HeadingBase: defaultHeadingBase,
+		Discovery:   "cargo-unknown",
+		Jobs:        1,
+		Toolchain:   "nightly",
+	}
+	if err := run(opts); err == nil {
+		t.Errorf("expected an error with an invalid -discovery")
+	}
+}
+
+func TestBatches(t *testing.T) {
+	var crates []*crate
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		crates = append(crates, &crate{Name: name})
+	}
+	for _, test := range []struct {
+		Size int
+		Want [][]string
+	}{
+		{0, [][]string{{"a", "b", "c", "d", "e"}}},
+		{1, [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}},
+		{2, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}},
+		{10, [][]string{{"a", "b", "c", "d", "e"}}},
+	} {
+		var got [][]string
+		for _, batch := range batches(crates, test.Size) {
+			var names []string
+			for _, c := range batch {
+				names = append(names, c.Name)
+			}
+			got = append(got, names)
+		}
+		if diff := cmp.Diff(test.Want, got); diff != "" {
+			t.Errorf("mismatch for size=%d (-want, +got):\n%s", test.Size, diff)
+		}
+	}
+	if got := batches(nil, 0); got != nil {
+		t.Errorf("expected no batches for an empty list, got=%v", got)
+	}
+}
+
+func TestGenerateConcurrentPages(t *testing.T) {
+	render := func(jobs int) map[string]string {
+		t.Helper()
+		input, err := testDataPublicCA()
+		if err != nil {
+			t.Fatal(err)
+		}
+		input.Jobs = jobs
+		outDir := t.TempDir()
+		if err := generate(input, outDir); err != nil {
+			t.Fatal(err)
+		}
+		entries, err := os.ReadDir(outDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := map[string]string{}
+		for _, e := range entries {
+			// The update time changes between runs.
+			if e.Name() == "docs.metadata" {
+				continue
+			}
+			contents, err := os.ReadFile(filepath.Join(outDir, e.Name()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			files[e.Name()] = string(contents)
+		}
+		return files
+	}
+	want := render(1)
+	got := render(4)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatched output with concurrent pages (-want, +got):\n%s", diff)
+	}
+}
+
+func TestRunInvalidToolchain(t *testing.T) {
+	opts := &options{
+		Out:         "docfx",
+		ProjectRoot: t.TempDir(),
+		HeadingBase: defaultHeadingBase,
+		Discovery:   discoveryCargoMetadata,
+		Jobs:        1,
+		Toolchain:   "stable",
+	}
+	if err := run(opts); err == nil {
+		t.Errorf("expected an error with a stable toolchain")
+	}
+}
+
+func TestRunOfflineUploadFailure(t *testing.T) {
+	opts := offlineOptions(t)
+	opts.Upload = "test-bucket"
+	fakeDocuploader(t, 1)
+	err := run(opts)
+	if err == nil || !strings.Contains(err.Error(), "error uploading crate google-cloud-security-publicca-v1") {
+		t.Errorf("expected an upload error, got=%v", err)
+	}
+}
+
+// offlineOptions returns the options to generate the publicca crate from the
+// test data, with a new project root.
+func offlineOptions(t *testing.T) *options {
+	t.Helper()
+	location, err := filepath.Abs("../../../src/generated/cloud/security/publicca/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	workspace, err := json.Marshal([]map[string]any{
+		{"name": "google-cloud-security-publicca-v1", "version": "1.0.0", "location": location},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	projectRoot := t.TempDir()
+	workspaceFile := filepath.Join(projectRoot, "plan.json")
+	if err := os.WriteFile(workspaceFile, workspace, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return &options{
+		Out:         "docfx",
+		ProjectRoot: projectRoot,
+		HeadingBase: defaultHeadingBase,
+		Discovery:   discoveryCargoMetadata,
+		Jobs:        1,
+		Toolchain:   "nightly",
+		RustdocDir:  "testdata",
+		Workspace:   workspaceFile,
+	}
+}
+
+// fakeDocuploader installs a fake `docuploader` in the PATH. Its uploads exit
+// with `code`, and each upload is recorded in the returned file.
+func fakeDocuploader(t *testing.T, code int) string {
+	t.Helper()
+	bin := t.TempDir()
+	calls := filepath.Join(bin, "calls.txt")
+	script := fmt.Sprintf("#!/bin/sh\nif [ \"$1\" = upload ]; then echo \"$@\" >> %s; exit %d; fi\nexit 0\n", calls, code)
+	if err := os.WriteFile(filepath.Join(bin, "docuploader"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return calls
+}
+
+func TestRunOfflineUploadAfterLocalRun(t *testing.T) {
+	opts := offlineOptions(t)
+	calls := fakeDocuploader(t, 0)
+	if err := run(opts); err != nil {
+		t.Fatal(err)
+	}
+	// The same output directory and cache manifest, now uploading the
+	// results.
+	opts.Upload = "test-bucket"
+	if err := run(opts); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatalf("expected the crate to be uploaded: %v", err)
+	}
+	if want := "--staging-bucket=test-bucket"; !strings.Contains(string(contents), want) {
+		t.Errorf("expected %q in the docuploader calls, got=%s", want, contents)
+	}
+
+	// Nothing changed since the last upload, the crate is skipped.
+	if err := os.Remove(calls); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(calls); err == nil {
+		t.Errorf("expected the unchanged crate to be skipped")
+	}
+}
+
+func TestRunOfflineChangedAsset(t *testing.T) {
+	opts := offlineOptions(t)
+	location := filepath.Join(opts.ProjectRoot, "publicca")
+	if err := os.MkdirAll(location, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(location, "README.md"), []byte("# Test\n\n![logo](logo.png)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	logo := filepath.Join(location, "logo.png")
+	if err := os.WriteFile(logo, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workspace, err := json.Marshal([]map[string]any{
+		{"name": "google-cloud-security-publicca-v1", "version": "1.0.0", "location": location},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(opts.Workspace, workspace, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(opts); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(opts.ProjectRoot, "docfx", "google-cloud-security-publicca-v1", "assets", "logo.png")
+	if got, err := os.ReadFile(output); err != nil || string(got) != "v1" {
+		t.Fatalf("mismatch in copied asset, want=v1, got=%s, err=%v", got, err)
+	}
+
+	// Only the asset changes, the crate is generated again.
+	if err := os.WriteFile(logo, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(opts); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(output); err != nil || string(got) != "v2" {
+		t.Errorf("mismatch in copied asset, want=v2, got=%s, err=%v", got, err)
+	}
+}
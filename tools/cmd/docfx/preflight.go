@@ -18,22 +18,44 @@ import (
 	"errors"
 	"fmt"
 	"os/exec"
+	"regexp"
+	"strings"
 )
 
 // preFlightTests() verifies all the required commands are available.
-func preFlightTests(upload string) error {
+func preFlightTests(opts *options) error {
+	if opts.Since != "" {
+		if err := testExternalCommand("git", "--version"); err != nil {
+			return fmt.Errorf("got an error trying to run `git --version`, -since requires git: %w", err)
+		}
+	}
+	if opts.offline() {
+		return preFlightUpload(opts.Upload)
+	}
 	if err := testExternalCommand("cargo", "--version"); err != nil {
 		return fmt.Errorf("got an error trying to run `cargo --version`, the instructions on https://www.rust-lang.org/learn/get-started may solve this problem: %w", err)
 	}
-	if err := testExternalCommand("cargo", "+nightly", "--version"); err != nil {
-		return fmt.Errorf("got an error trying to run `cargo +nightly --version`, run `rustup install nightly` to solve this problem: %w", err)
+	if err := testToolchain(opts.Toolchain); err != nil {
+		return err
 	}
-	if err := testExternalCommand("cargo", "+nightly", "rustdoc", "--help"); err != nil {
-		return fmt.Errorf("got an error trying to run `cargo +nightly rustdoc --help`, maybe running `rustup update nightly` will solve this problem: %w", err)
+	toolchain := "+" + opts.Toolchain
+	if err := testExternalCommand("cargo", toolchain, "--version"); err != nil {
+		return fmt.Errorf("got an error trying to run `cargo %s --version`, run `rustup toolchain install %s` to solve this problem: %w", toolchain, opts.Toolchain, err)
 	}
-	if err := testExternalCommand("cargo", "workspaces", "--version"); err != nil {
-		return fmt.Errorf("got an error trying to run `cargo workspaces --version`, run `cargo install --locked cargo-workspaces` to solve this problem: %w", err)
+	if err := testExternalCommand("cargo", toolchain, "rustdoc", "--help"); err != nil {
+		return fmt.Errorf("got an error trying to run `cargo %s rustdoc --help`, maybe running `rustup toolchain install %s` will solve this problem: %w", toolchain, opts.Toolchain, err)
+	}
+	if opts.Workspace == "" && opts.Discovery == discoveryCargoWorkspaces {
+		if err := testExternalCommand("cargo", "workspaces", "--version"); err != nil {
+			return fmt.Errorf("got an error trying to run `cargo workspaces --version`, run `cargo install --locked cargo-workspaces` to solve this problem: %w", err)
+		}
 	}
+	return preFlightUpload(opts.Upload)
+}
+
+// preFlightUpload verifies the commands to upload the results are available,
+// if needed.
+func preFlightUpload(upload string) error {
 	if upload == "" {
 		return nil
 	}
@@ -54,3 +76,41 @@ func testExternalCommand(c string, arg ...string) error {
 	}
 	return nil
 }
+
+// testToolchain verifies the toolchain is installed. Recent versions of
+// rustup install missing toolchains on demand, which would hide a typo in
+// the toolchain name, or silently use a different nightly in each build.
+func testToolchain(toolchain string) error {
+	output, err := exec.Command("rustup", "toolchain", "list").Output()
+	if err != nil {
+		return fmt.Errorf("got an error trying to run `rustup toolchain list`, the instructions on https://www.rust-lang.org/learn/get-started may solve this problem: %w", err)
+	}
+	if !toolchainInstalled(string(output), toolchain) {
+		return fmt.Errorf("the %s toolchain is not installed, run `rustup toolchain install %s` to solve this problem", toolchain, toolchain)
+	}
+	return nil
+}
+
+// toolchainInstalled returns true if `toolchain` appears in `list`, the output
+// of `rustup toolchain list`. The installed toolchains include the host
+// triple, e.g. `nightly-2025-08-01-x86_64-unknown-linux-gnu (default)`.
+func toolchainInstalled(list, toolchain string) bool {
+	for _, line := range strings.Split(list, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		installed := fields[0]
+		if installed == toolchain {
+			return true
+		}
+		// With `nightly`, ignore dated toolchains such as
+		// `nightly-2025-08-01-x86_64-unknown-linux-gnu`.
+		if host, ok := strings.CutPrefix(installed, toolchain+"-"); ok && !toolchainDate.MatchString(host) {
+			return true
+		}
+	}
+	return false
+}
+
+var toolchainDate = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}(-|$)`)
@@ -0,0 +1,44 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "testing"
+
+func TestToolchainInstalled(t *testing.T) {
+	list := `stable-x86_64-unknown-linux-gnu (default)
+nightly-2025-08-01-x86_64-unknown-linux-gnu
+1.85-x86_64-unknown-linux-gnu
+`
+	for _, test := range []struct {
+		Toolchain string
+		Want      bool
+	}{
+		{"stable", true},
+		{"nightly-2025-08-01", true},
+		{"nightly-2025-08-01-x86_64-unknown-linux-gnu", true},
+		{"1.85", true},
+		{"nightly", false},
+		{"nightly-2025-08-02", false},
+		{"beta", false},
+	} {
+		if got := toolchainInstalled(list, test.Toolchain); got != test.Want {
+			t.Errorf("mismatch for %s, want=%v, got=%v", test.Toolchain, test.Want, got)
+		}
+	}
+
+	if !toolchainInstalled("nightly-x86_64-unknown-linux-gnu\n", "nightly") {
+		t.Errorf("expected the undated nightly toolchain to be installed")
+	}
+}
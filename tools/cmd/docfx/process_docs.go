@@ -17,30 +17,116 @@ package main
 import (
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 	"unicode"
 
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
 	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 )
 
 type state struct {
-	Indent int
+	// Prefix is written before each line in the block, e.g. the indentation
+	// for list items or the `> ` marker for block quotes.
+	Prefix string
+	// Marker replaces the end of Prefix in the first line of the block, e.g.
+	// the `- ` marker for list items.
 	Marker string
 }
 
+// defaultHeadingBase is the level for the top-level headings in docstrings.
+// The page title, the member sections, and the member names use the levels
+// above it.
+const defaultHeadingBase = 4
+
+// docContext holds the state to process all the docstrings in a page.
+type docContext struct {
+	// HeadingBase is the level used for the top-level (`#`) headings in
+	// docstrings. Other headings are shifted by the same amount.
+	HeadingBase int
+	// Root is the directory used to resolve relative links to local files,
+	// typically the crate location.
+	Root string
+	// Boundary is the directory containing all the local files that may be
+	// copied to the output, typically the project root.
+	Boundary string
+	// crate and pageId are used to resolve links to rustdoc pages.
+	crate  *crate
+	pageId string
+	// anchors contains the heading anchors used in the page.
+	anchors map[string]bool
+	// assets maps the local files referenced in the page, relative to the
+	// output directory, to their source location.
+	assets map[string]string
+}
+
+// anchor returns an anchor for `id` that is unique in the page.
+func (ctx *docContext) anchor(id string) string {
+	anchor := id
+	for i := 1; ctx.anchors[anchor]; i++ {
+		anchor = fmt.Sprintf("%s-%d", id, i)
+	}
+	ctx.anchors[anchor] = true
+	return anchor
+}
+
+// headingAnchors assigns an anchor, unique in the page, to each heading in
+// `doc`. Returns the anchor for each heading, and the fragments, e.g.
+// `#examples`, that must be rewritten to link to the renamed anchors.
+func (ctx *docContext) headingAnchors(doc ast.Node) (map[*ast.Heading]string, map[string]string) {
+	if ctx == nil {
+		return nil, nil
+	}
+	anchors := map[*ast.Heading]string{}
+	fragments := map[string]string{}
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := node.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+		if id, ok := heading.AttributeString("id"); ok {
+			original := string(id.([]byte))
+			anchors[heading] = ctx.anchor(original)
+			if anchors[heading] != original {
+				fragments["#"+original] = "#" + anchors[heading]
+			}
+		}
+		return ast.WalkSkipChildren, nil
+	})
+	return anchors, fragments
+}
+
+// processDocString processes a standalone docstring, without changing the
+// heading levels.
 func processDocString(contents string) (string, error) {
+	return processDocStringWithContext(contents, nil)
+}
+
+// processDocStringWithContext processes a docstring in the context of a
+// page. If `ctx` is nil the headings are preserved.
+func processDocStringWithContext(contents string, ctx *docContext) (string, error) {
 	var results []string
+	// The extensions enabled by rustdoc, see
+	// https://doc.rust-lang.org/rustdoc/how-to-write-documentation.html#markdown
 	md := goldmark.New(
 		goldmark.WithParserOptions(
 			parser.WithAutoHeadingID(),
 		),
-		goldmark.WithExtensions(),
+		goldmark.WithExtensions(
+			extension.Table,
+			extension.Strikethrough,
+			extension.TaskList,
+			extension.Footnote,
+		),
 	)
 	documentationBytes := []byte(contents)
-	doc := md.Parser().Parse(text.NewReader(documentationBytes))
+	pc := parser.NewContext()
+	doc := md.Parser().Parse(text.NewReader(documentationBytes), parser.WithContext(pc))
 
 	// We store the state in a stack. This allows for backtracking as we
 	// walk the AST.
@@ -52,16 +138,19 @@ func processDocString(contents string) (string, error) {
 	print_marker := false
 	// And a flag for when we need an extra line break between blocks.
 	print_previous_blank := false
+	// The fence for the current code block, code blocks have no children.
+	fence := ""
 
 	// Write a new line, given the current state.
 	add_line := func(l string) {
 		state := states[len(states)-1]
 		if print_marker {
-			spaces := strings.Repeat(" ", state.Indent-len(state.Marker))
-			results = append(results, fmt.Sprintf("%s%s%s", spaces, state.Marker, l))
+			// The marker may be longer than the prefix, e.g. `[^note]: `
+			// in footnotes.
+			prefix := state.Prefix[:max(0, len(state.Prefix)-len(state.Marker))]
+			results = append(results, fmt.Sprintf("%s%s%s", prefix, state.Marker, l))
 		} else {
-			spaces := strings.Repeat(" ", state.Indent)
-			results = append(results, fmt.Sprintf("%s%s", spaces, l))
+			results = append(results, fmt.Sprintf("%s%s", state.Prefix, l))
 		}
 		// Avoid printing extra markers in the case of multi-line or
 		// multi-paragraph list items.
@@ -69,81 +158,217 @@ func processDocString(contents string) (string, error) {
 		// We wrote something. Accept an extra line break between blocks.
 		print_previous_blank = true
 	}
-
-	err := ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
-		// A copy of the current state
+	// Write an empty line between blocks, given the current state.
+	add_blank := func() {
+		results = append(results, states[len(states)-1].Prefix)
+		// Disallow consecutive empty lines.
+		print_previous_blank = false
+	}
+	// Push a new state for a block with a prefix and an optional marker.
+	push_state := func(prefix, marker string) {
 		state := states[len(states)-1]
+		if print_marker {
+			// The block starts in the same line as the parent marker, e.g.
+			// a block quote in a list item.
+			marker = state.Marker + marker
+		}
+		state.Prefix += prefix
+		state.Marker = marker
+		states = append(states, state)
+	}
+
+	// Find any inline HTML that is not allowed. We escape the opening `<`
+	// when writing the lines containing these tags.
+	escaped := map[int]bool{}
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if raw, ok := node.(*ast.RawHTML); ok && entering && raw.Segments.Len() != 0 {
+			var tag []string
+			for i := 0; i < raw.Segments.Len(); i++ {
+				segment := raw.Segments.At(i)
+				tag = append(tag, string(segment.Value(documentationBytes)))
+			}
+			if !allowedHTML(strings.Join(tag, "")) {
+				escaped[raw.Segments.At(0).Start] = true
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	// Assign the heading anchors before rewriting the links, as links to
+	// renamed anchors must be rewritten too.
+	anchors, fragments := ctx.headingAnchors(doc)
+	// Find any links to local files, rustdoc pages or renamed anchors, these
+	// are rewritten to work in the generated pages.
+	rewrites, err := ctx.rewriteLinks(doc, pc, contents, fragments)
+	if err != nil {
+		return "", err
+	}
+	line_value := func(segment text.Segment) string {
+		var line strings.Builder
+		for i := segment.Start; i < segment.Stop; i++ {
+			if escaped[i] {
+				line.WriteString("&lt;")
+				continue
+			}
+			line.WriteByte(documentationBytes[i])
+		}
+		return rewrites.Replace(line.String())
+	}
+
+	// The open HTML alerts, see `htmlAlert`.
+	var alerts []*htmlAlert
+	// Close the innermost HTML alert.
+	close_alert := func() {
+		// Remove any trailing blank lines in the alert.
+		prefix := states[len(states)-1].Prefix
+		for len(results) != 0 && results[len(results)-1] == prefix {
+			results = results[:len(results)-1]
+		}
+		states = states[:len(states)-1]
+		alerts = alerts[:len(alerts)-1]
+		print_previous_blank = true
+	}
+	// Write a line from an HTML block, converting any HTML alerts.
+	add_html_line := func(l string) {
+		if alert, rest := htmlAlertStart(l); alert != nil {
+			push_state("> ", "> ")
+			print_marker = true
+			add_line(fmt.Sprintf("[!%s]", alert.Kind))
+			alerts = append(alerts, alert)
+			if rest == "" {
+				return
+			}
+			l = rest
+		}
+		if len(alerts) != 0 {
+			alert := alerts[len(alerts)-1]
+			trimmed := strings.TrimSpace(l)
+			if title, ok := htmlSummary(trimmed); ok && alert.Tag == "details" {
+				add_line(fmt.Sprintf("**%s**", sanitizeHTML(title)))
+				add_blank()
+				return
+			}
+			if htmlOpensTag(trimmed, alert.Tag) {
+				alert.Depth++
+			}
+			if text, ok := strings.CutSuffix(trimmed, fmt.Sprintf("</%s>", alert.Tag)); ok {
+				if alert.Depth != 0 {
+					alert.Depth--
+				} else {
+					// The closing tag may follow some text, e.g. in
+					// `<div class="warning">Text</div>`.
+					if text = strings.TrimSpace(text); text != "" {
+						add_line(sanitizeHTML(text))
+					}
+					close_alert()
+					return
+				}
+			}
+		}
+		add_line(sanitizeHTML(l))
+	}
 
+	err = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
 		// First handle blank lines between blocks
 		switch node.Kind() {
-		case ast.KindCodeBlock,
+		case ast.KindBlockquote,
+			ast.KindCodeBlock,
 			ast.KindFencedCodeBlock,
 			ast.KindHTMLBlock,
 			ast.KindHeading,
 			ast.KindList,
 			ast.KindListItem,
 			ast.KindParagraph,
-			ast.KindTextBlock:
+			ast.KindTextBlock,
+			ast.KindThematicBreak:
+			if node.Kind() == ast.KindParagraph && node.Lines().Len() == 0 {
+				// Paragraphs that only contain reference definitions are
+				// left empty by the parser.
+				return ast.WalkSkipChildren, nil
+			}
 			if entering && node.HasBlankPreviousLines() && print_previous_blank {
-				results = append(results, "")
-				// Disallow consecutive empty lines.
-				print_previous_blank = false
+				add_blank()
+			}
+		case east.KindTable, east.KindFootnote:
+			// Tables are created from paragraphs and footnote definitions
+			// are moved to the end of the document. Always separate them
+			// from the previous block.
+			if entering && print_previous_blank {
+				add_blank()
 			}
 		}
 
 		switch node.Kind() {
 		case ast.KindDocument:
 			// The root block. There is nothing to render.
-		case ast.KindHTMLBlock,
-			ast.KindParagraph,
+		case ast.KindParagraph,
 			ast.KindTextBlock:
 			// We will dump the contents from these blocks, skipping
 			// any children. This saves us from having to parse all
 			// inline blocks, e.g. an **emphasis** block.
 			if entering {
 				for i := 0; i < node.Lines().Len(); i++ {
-					line := node.Lines().At(i)
-					line_str := string(line.Value(documentationBytes))
-					add_line(line_str)
+					add_line(line_value(node.Lines().At(i)))
+				}
+			}
+			return ast.WalkSkipChildren, nil
+		case ast.KindHTMLBlock:
+			if entering {
+				html := node.(*ast.HTMLBlock)
+				for i := 0; i < node.Lines().Len(); i++ {
+					add_html_line(line_value(node.Lines().At(i)))
+				}
+				if html.HasClosure() {
+					add_html_line(line_value(html.ClosureLine))
 				}
 			}
 			return ast.WalkSkipChildren, nil
 		case ast.KindFencedCodeBlock:
 			if entering {
-				lang := "rust"
 				fcb := node.(*ast.FencedCodeBlock)
+				info := parseCodeBlockInfo("")
 				if fcb.Info != nil {
-					info := string(fcb.Info.Value(documentationBytes))
-					if info != "no_run" {
-						// "no_run" is a tag for not running Rust code.
-						lang = info
-					}
+					info = parseCodeBlockInfo(string(fcb.Info.Value(documentationBytes)))
+				}
+				if info.Label != "" {
+					add_line(fmt.Sprintf("_%s_", info.Label))
+					add_line("")
 				}
-				add_line("```" + lang)
+				var lines []string
 				for i := 0; i < fcb.Lines().Len(); i++ {
 					line := fcb.Lines().At(i)
-					line_str := string(line.Value(documentationBytes))
-					trimmed := strings.TrimLeftFunc(line_str, unicode.IsSpace)
-					if (lang == "rust" || lang == "rs") && len(trimmed) > 0 && trimmed[0] == '#' {
-						// Filter out code lines starting with a '#'
-						continue
+					lines = append(lines, string(line.Value(documentationBytes)))
+				}
+				fence = codeFence(lines)
+				add_line(fence + info.Lang)
+				for _, line_str := range lines {
+					if info.IsRust {
+						var hidden bool
+						if line_str, hidden = hiddenRustLine(line_str); hidden {
+							continue
+						}
 					}
 					add_line(line_str)
 				}
 			} else {
-				add_line("```")
+				add_line(fence)
 			}
 		case ast.KindHeading:
 			if entering {
 				heading := node.(*ast.Heading)
-				state.Marker = strings.Repeat("#", heading.Level) + " "
-				state.Indent += len(state.Marker)
+				level := heading.Level
+				anchor := ""
+				if ctx != nil {
+					level = min(level+ctx.HeadingBase-1, 6)
+					if a, ok := anchors[heading]; ok {
+						anchor = fmt.Sprintf(`<a id="%s"></a>`, a)
+					}
+				}
+				marker := strings.Repeat("#", level) + " "
+				push_state(strings.Repeat(" ", len(marker)), marker)
 				print_marker = true
-				states = append(states, state)
 				for i := 0; i < node.Lines().Len(); i++ {
-					line := node.Lines().At(i)
-					line_str := string(line.Value(documentationBytes))
-					add_line(line_str)
+					add_line(anchor + line_value(node.Lines().At(i)))
+					anchor = ""
 				}
 			} else {
 				states = states[:len(states)-1]
@@ -154,13 +379,12 @@ func processDocString(contents string) (string, error) {
 				list := node.(*ast.List)
 				marker := string(list.Marker)
 				if list.IsOrdered() {
-					marker = "1."
+					// Preserve the start number. The remaining items use
+					// the same number, so the marker never changes length.
+					marker = fmt.Sprintf("%d%c", list.Start, list.Marker)
 				}
-				state.Marker = marker + " "
-				// It is simpler to set the indent now. Note that we never
-				// count past 1, so the marker is not increasing in length.
-				state.Indent += len(state.Marker)
-				states = append(states, state)
+				marker += " "
+				push_state(strings.Repeat(" ", len(marker)), marker)
 			} else {
 				states = states[:len(states)-1]
 			}
@@ -168,11 +392,23 @@ func processDocString(contents string) (string, error) {
 			// Restore the marker, which might have been cleared if the
 			// item has multi-line text blocks.
 			print_marker = true
+		case ast.KindBlockquote:
+			if entering {
+				push_state("> ", "> ")
+				print_marker = true
+			} else {
+				states = states[:len(states)-1]
+			}
+		case ast.KindThematicBreak:
+			if entering {
+				// Unlike `---`, this cannot be confused with a setext
+				// heading underline.
+				add_line("***")
+			}
 		case ast.KindCodeBlock:
 			// https://spec.commonmark.org/0.31.2/#indented-code-block
 			if entering {
-				state.Indent += 4
-				states = append(states, state)
+				push_state("    ", "")
 				for i := 0; i < node.Lines().Len(); i++ {
 					line := node.Lines().At(i)
 					line_str := string(line.Value(documentationBytes))
@@ -181,10 +417,26 @@ func processDocString(contents string) (string, error) {
 			} else {
 				states = states[:len(states)-1]
 			}
+		case east.KindTable:
+			if entering {
+				for _, line := range tableLines(node.(*east.Table), line_value) {
+					add_line(line)
+				}
+			}
+			return ast.WalkSkipChildren, nil
+		case east.KindFootnoteList:
+			// The container for all the footnote definitions. There is
+			// nothing to render.
+		case east.KindFootnote:
+			if entering {
+				footnote := node.(*east.Footnote)
+				push_state("    ", fmt.Sprintf("[^%s]: ", footnote.Ref))
+				print_marker = true
+			} else {
+				states = states[:len(states)-1]
+			}
 		default:
 			if entering {
-				fmt.Printf("\n\nKind: %d", node.Kind())
-				node.Dump(documentationBytes, 2)
 				return ast.WalkStop, fmt.Errorf("encountered unknown NodeKind: %s", node.Kind().String())
 			}
 		}
@@ -193,6 +445,11 @@ func processDocString(contents string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	// Close any unbalanced alerts, so their state does not leak into the
+	// reference links.
+	for len(alerts) != 0 {
+		close_alert()
+	}
 
 	for i, line := range results {
 		// Many lines end in a newline, but we are handling new lines
@@ -200,20 +457,391 @@ func processDocString(contents string) (string, error) {
 		results[i] = strings.TrimRightFunc(line, unicode.IsSpace)
 	}
 
-	// Append reference links. These are skipped by the AST.
-	results = append(results, referenceLinks(contents)...)
+	// Append reference links. These are not part of the AST.
+	if references := referenceLinks(pc, rewrites); len(references) != 0 {
+		if len(results) != 0 && results[len(results)-1] != "" {
+			results = append(results, "")
+		}
+		results = append(results, references...)
+	}
 	return strings.Join(results, "\n"), nil
 }
 
+// codeBlockInfo describes a fenced code block, based on its info string.
+type codeBlockInfo struct {
+	// Lang is the language used for syntax highlighting.
+	Lang string
+	// IsRust is true if rustdoc treats the block as a Rust doc test.
+	IsRust bool
+	// Label describes how the example behaves, if the behavior is unusual.
+	Label string
+}
+
+// rustdocCodeBlockLabels are the labels for rustdoc attributes that change
+// how an example behaves.
+var rustdocCodeBlockLabels = map[string]string{
+	"should_panic": "This example panics.",
+	"compile_fail": "This example deliberately fails to compile.",
+	"ignore":       "This example is not tested.",
+}
+
+// parseCodeBlockInfo parses the info string of a fenced code block.
+//
+// Rustdoc treats the code blocks as Rust code unless one of the comma or
+// space separated tokens is not a rustdoc attribute, see
+// https://doc.rust-lang.org/rustdoc/write-documentation/documentation-tests.html#attributes
+func parseCodeBlockInfo(info string) codeBlockInfo {
+	result := codeBlockInfo{Lang: "rust", IsRust: true}
+	tokens := strings.FieldsFunc(info, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+	for _, token := range tokens {
+		switch {
+		case token == "rust" || token == "rs":
+		case token == "no_run" || token == "test_harness" || token == "standalone_crate" || token == "allow_fail":
+		case strings.HasPrefix(token, "edition"):
+		case strings.HasPrefix(token, "ignore-"):
+			// Ignored only in some targets.
+		case strings.HasPrefix(token, "{") || strings.HasPrefix(token, "."):
+			// Custom CSS classes, these do not affect the language.
+		case rustdocCodeBlockLabels[token] != "":
+			if result.Label == "" {
+				result.Label = rustdocCodeBlockLabels[token]
+			}
+		default:
+			if result.IsRust {
+				// The first unknown token is the language.
+				result.Lang = token
+				result.IsRust = false
+			}
+		}
+	}
+	if !result.IsRust {
+		result.Label = ""
+	}
+	return result
+}
+
+// hiddenRustLine returns true if `line` is hidden in Rust doc tests. Lines
+// starting with `##` are escapes for lines starting with `#`, the function
+// returns the line with the escape removed.
+//
+// See https://doc.rust-lang.org/rustdoc/write-documentation/documentation-tests.html#hiding-portions-of-the-example
+func hiddenRustLine(line string) (string, bool) {
+	trimmed := strings.TrimLeftFunc(line, unicode.IsSpace)
+	indent := line[:len(line)-len(trimmed)]
+	switch {
+	case strings.HasPrefix(trimmed, "##"):
+		return indent + trimmed[1:], false
+	case strings.TrimRightFunc(trimmed, unicode.IsSpace) == "#" || strings.HasPrefix(trimmed, "# ") || strings.HasPrefix(trimmed, "#\t"):
+		return line, true
+	}
+	return line, false
+}
+
+// tableLines formats a GFM table. The cells are copied verbatim, including
+// any inline markup.
+func tableLines(table *east.Table, value func(text.Segment) string) []string {
+	row := func(node ast.Node) string {
+		var cells []string
+		for cell := node.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			var content []string
+			for i := 0; i < cell.Lines().Len(); i++ {
+				content = append(content, value(cell.Lines().At(i)))
+			}
+			cells = append(cells, strings.Join(content, " "))
+		}
+		return fmt.Sprintf("| %s |", strings.Join(cells, " | "))
+	}
+	var lines []string
+	for node := table.FirstChild(); node != nil; node = node.NextSibling() {
+		lines = append(lines, row(node))
+		if node.Kind() != east.KindTableHeader {
+			continue
+		}
+		var delimiters []string
+		for _, a := range table.Alignments {
+			switch a {
+			case east.AlignLeft:
+				delimiters = append(delimiters, ":---")
+			case east.AlignRight:
+				delimiters = append(delimiters, "---:")
+			case east.AlignCenter:
+				delimiters = append(delimiters, ":---:")
+			default:
+				delimiters = append(delimiters, "---")
+			}
+		}
+		lines = append(lines, fmt.Sprintf("| %s |", strings.Join(delimiters, " | ")))
+	}
+	return lines
+}
+
 var referenceLinkMatcher = regexp.MustCompile(`^\[([^\]]+)\]:\s*(.*)$`)
 
-func referenceLinks(contents string) []string {
+// referenceLinks returns the reference link definitions found by the parser,
+// sorted by label. The destinations are rewritten as needed.
+func referenceLinks(pc parser.Context, rewrites *linkRewrites) []string {
+	references := pc.References()
+	slices.SortFunc(references, func(a, b parser.Reference) int {
+		return strings.Compare(string(a.Label()), string(b.Label()))
+	})
 	var results []string
-	lines := strings.Split(contents, "\n")
-	for _, line := range lines {
-		if len(referenceLinkMatcher.FindStringSubmatch(line)) > 0 {
-			results = append(results, line)
+	for _, r := range references {
+		destination := rewrites.destination(string(r.Destination()))
+		if destination == "" || strings.ContainsAny(destination, " <>") {
+			destination = fmt.Sprintf("<%s>", destination)
 		}
+		line := fmt.Sprintf("[%s]: %s", r.Label(), destination)
+		if r.Title() != nil {
+			line = fmt.Sprintf("%s %q", line, r.Title())
+		}
+		results = append(results, line)
 	}
 	return results
 }
+
+var sentenceEnd = regexp.MustCompile(`[.!?](\s|$)`)
+
+// fieldBehaviorLabels are the prefixes used in the documentation of proto
+// fields.
+var fieldBehaviorLabels = []string{
+	"Required.", "Optional.", "Output only.", "Input only.", "Immutable.", "Identifier.",
+	"Required. Immutable.", "Optional. Immutable.", "Output only. Immutable.",
+}
+
+// docSummary returns the first sentence of a docstring as a single line.
+//
+// The sentence is taken from the first paragraph, as found by
+// `splitSummary()`, so it matches the start of the item summary.
+//
+// This is used in tables and other places where the full docstring is too
+// long. Reference-style links are replaced by their text, as the reference
+// definitions are not available in these contexts.
+func docSummary(contents string) string {
+	paragraph, _ := splitSummary(contents)
+	summary := strings.Join(strings.Fields(paragraph), " ")
+	for _, loc := range sentenceEnd.FindAllStringIndex(summary, -1) {
+		// Skip labels such as "Required." or "Output only." which are
+		// common in the documentation generated from protos.
+		if !slices.Contains(fieldBehaviorLabels, summary[:loc[0]+1]) {
+			summary = summary[:loc[0]+1]
+			break
+		}
+	}
+	summary = removeReferenceLinks(summary)
+	return strings.ReplaceAll(summary, "|", `\|`)
+}
+
+// removeReferenceLinks replaces `[text][ref]`, `[text][]` and `[text]` with
+// `text`. Inline links, such as `[text](url)`, are preserved.
+func removeReferenceLinks(s string) string {
+	var result strings.Builder
+	for {
+		start := strings.Index(s, "[")
+		if start == -1 {
+			break
+		}
+		end := strings.Index(s[start:], "]")
+		if end == -1 {
+			break
+		}
+		end += start
+		rest := s[end+1:]
+		if strings.HasPrefix(rest, "(") {
+			// An inline link, preserve it.
+			result.WriteString(s[:end+1])
+			s = rest
+			continue
+		}
+		result.WriteString(s[:start])
+		result.WriteString(s[start+1 : end])
+		if strings.HasPrefix(rest, "[") {
+			if close := strings.Index(rest, "]"); close != -1 {
+				rest = rest[close+1:]
+			}
+		}
+		s = rest
+	}
+	result.WriteString(s)
+	return result.String()
+}
+
+// docSections holds a processed docstring split into the sections used by
+// DocFX.
+type docSections struct {
+	// Summary is the first paragraph, as in the rustdoc item summaries.
+	Summary string
+	// Remarks contains any other paragraphs and sections.
+	Remarks string
+	// Examples contains the `# Examples` sections, if any.
+	Examples []string
+	// Errors contains the `# Errors` section, if any.
+	Errors string
+	// Panics contains the `# Panics` section, if any.
+	Panics string
+}
+
+var (
+	headingMatcher = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	headingAnchor  = regexp.MustCompile(`^<a id="[^"]*"></a>`)
+)
+
+// splitDocString splits a docstring, as returned by `processDocString()`,
+// into sections.
+//
+// The `# Examples`, `# Errors`, and `# Panics` sections follow the rustdoc
+// conventions, see https://doc.rust-lang.org/rustdoc/how-to-write-documentation.html.
+// Each section receives a copy of the reference link definitions it uses.
+func splitDocString(contents string) docSections {
+	var result docSections
+	var body, definitions []string
+	// The lines in the current special section and its destination.
+	var section []string
+	var sectionLevel int
+	var sectionName string
+	flush := func() {
+		if sectionName == "" {
+			return
+		}
+		text := strings.TrimSpace(strings.Join(section, "\n"))
+		switch sectionName {
+		case "examples":
+			if text != "" {
+				result.Examples = append(result.Examples, text)
+			}
+		case "errors":
+			result.Errors = text
+		case "panics":
+			result.Panics = text
+		}
+		section, sectionLevel, sectionName = nil, 0, ""
+	}
+	fence := ""
+	for _, line := range strings.Split(contents, "\n") {
+		if fence = nextFence(fence, line); fence == "" {
+			if referenceLinkMatcher.MatchString(line) {
+				definitions = append(definitions, line)
+				continue
+			}
+			if m := headingMatcher.FindStringSubmatch(line); m != nil {
+				level := len(m[1])
+				if sectionName != "" && level <= sectionLevel {
+					flush()
+				}
+				if name := docSectionName(headingAnchor.ReplaceAllString(m[2], "")); name != "" && sectionName == "" {
+					sectionName, sectionLevel = name, level
+					continue
+				}
+			}
+		}
+		if sectionName != "" {
+			section = append(section, line)
+		} else {
+			body = append(body, line)
+		}
+	}
+	flush()
+
+	summary, remarks := splitSummary(strings.Join(body, "\n"))
+	result.Summary = withDefinitions(summary, definitions)
+	result.Remarks = withDefinitions(remarks, definitions)
+	for i, e := range result.Examples {
+		result.Examples[i] = withDefinitions(e, definitions)
+	}
+	result.Errors = withDefinitions(result.Errors, definitions)
+	result.Panics = withDefinitions(result.Panics, definitions)
+	return result
+}
+
+// splitSummary splits a docstring into its first paragraph and the remaining
+// text. The first paragraph ends at the first blank line outside a code
+// block.
+func splitSummary(contents string) (string, string) {
+	lines := strings.Split(strings.TrimSpace(contents), "\n")
+	fence := ""
+	for i, line := range lines {
+		if fence = nextFence(fence, line); fence == "" && strings.TrimSpace(line) == "" {
+			return strings.Join(lines[:i], "\n"), strings.TrimSpace(strings.Join(lines[i:], "\n"))
+		}
+	}
+	return strings.Join(lines, "\n"), ""
+}
+
+// fenceMatcher matches the lines opening or closing a fenced code block.
+var fenceMatcher = regexp.MustCompile("^(`{3,}|~{3,})(.*)$")
+
+// nextFence returns the fence of the code block open after `line`, or the
+// empty string if there is none. `fence` is the fence of the code block open
+// before `line`.
+func nextFence(fence, line string) string {
+	m := fenceMatcher.FindStringSubmatch(strings.TrimSpace(line))
+	switch {
+	case m == nil:
+		return fence
+	case fence == "":
+		return m[1]
+	case m[1][0] == fence[0] && len(m[1]) >= len(fence) && m[2] == "":
+		return ""
+	}
+	return fence
+}
+
+// codeFence returns the fence for a code block with `lines`. The fence is
+// longer than any run of backticks in the code, which would otherwise close
+// the block.
+func codeFence(lines []string) string {
+	longest := 0
+	for _, line := range lines {
+		run := 0
+		for _, r := range line {
+			if r != '`' {
+				run = 0
+				continue
+			}
+			run++
+			longest = max(longest, run)
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// docSectionName returns the name of the special section for a heading, or
+// the empty string if the heading does not start a special section.
+func docSectionName(heading string) string {
+	switch strings.ToLower(strings.TrimSpace(heading)) {
+	case "example", "examples":
+		return "examples"
+	case "errors":
+		return "errors"
+	case "panics":
+		return "panics"
+	}
+	return ""
+}
+
+// linkLabelMatcher matches the text in brackets, which includes the labels for
+// reference links, e.g. `[Foo]`, `[text][Foo]` and `[Foo][]`.
+var linkLabelMatcher = regexp.MustCompile(`\[([^\[\]]+)\]`)
+
+// withDefinitions appends the reference link definitions used in `text`.
+func withDefinitions(text string, definitions []string) string {
+	if text == "" {
+		return text
+	}
+	// Link labels match case-insensitively and ignoring whitespace
+	// differences, as in the parser.
+	labels := map[string]bool{}
+	for _, m := range linkLabelMatcher.FindAllStringSubmatch(text, -1) {
+		labels[util.ToLinkReference([]byte(m[1]))] = true
+	}
+	var used []string
+	for _, d := range definitions {
+		label := referenceLinkMatcher.FindStringSubmatch(d)[1]
+		if labels[util.ToLinkReference([]byte(label))] {
+			used = append(used, d)
+		}
+	}
+	if len(used) == 0 {
+		return text
+	}
+	return text + "\n\n" + strings.Join(used, "\n")
+}
@@ -15,6 +15,7 @@
 package main
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -34,15 +35,105 @@ More text`
 	}
 }
 
-func TestPreserveHTML(t *testing.T) {
+func TestHTMLWarning(t *testing.T) {
 	input := "Google APIs eXtensions for Rust.\n\nThis crate contains a number of types and functions used in the\nimplementation of the Google Cloud Client Libraries for Rust.\n\n<div class=\"warning\">\nAll the types, traits, and functions defined in any module with `internal`\nin its name are <b>not</b> intended for general use. Such symbols will\nremain unstable for the foreseeable future, even if used in stable SDKs.\nWe (the Google Cloud Client Libraries for Rust team) control both and will\nchange both if needed.\n</div>"
-	want := input
+	want := "Google APIs eXtensions for Rust.\n\nThis crate contains a number of types and functions used in the\nimplementation of the Google Cloud Client Libraries for Rust.\n\n> [!WARNING]\n> All the types, traits, and functions defined in any module with `internal`\n> in its name are <b>not</b> intended for general use. Such symbols will\n> remain unstable for the foreseeable future, even if used in stable SDKs.\n> We (the Google Cloud Client Libraries for Rust team) control both and will\n> change both if needed."
 	got, err := processDocString(input)
 	if err != nil {
 		t.Fatal(err)
 	}
 	if diff := cmp.Diff(want, got); diff != "" {
-		t.Errorf("mismatch in processDocString for lists (-want, +got)\n:%s", diff)
+		t.Errorf("mismatch in processDocString for HTML warnings (-want, +got)\n:%s", diff)
+	}
+}
+
+func TestHTMLAlertsWithMarkdown(t *testing.T) {
+	input := `Leading text
+
+<div class="warning">
+
+A warning with **markdown**.
+
+- and a list
+
+</div>
+
+<details>
+<summary>More details</summary>
+
+The details.
+
+</details>
+
+More text`
+	want := `Leading text
+
+> [!WARNING]
+>
+> A warning with **markdown**.
+>
+> - and a list
+
+> [!NOTE]
+> **More details**
+>
+> The details.
+
+More text`
+	got, err := processDocString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch in processDocString for HTML alerts (-want, +got)\n:%s", diff)
+	}
+}
+
+func TestHTMLAlertsSingleLineAndUnclosed(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		want  string
+	}{
+		{
+			input: "<div class=\"warning\">A one-line warning.</div>\n\nMore text.",
+			want:  "> [!WARNING]\n> A one-line warning.\n\nMore text.",
+		},
+		{
+			input: "<div class=\"warning\">\n<divider>nested</divider>\n</div>\n\nMore text.",
+			want:  "> [!WARNING]\n> &lt;divider>nested&lt;/divider>\n\nMore text.",
+		},
+		{
+			input: "<div class=\"warning\">\nNever closed.\n\n[Foo]: https://example.com/foo",
+			want:  "> [!WARNING]\n> Never closed.\n\n[Foo]: https://example.com/foo",
+		},
+	} {
+		got, err := processDocString(test.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("mismatch in processDocString(%q) (-want, +got)\n:%s", test.input, diff)
+		}
+	}
+}
+
+func TestSanitizeHTML(t *testing.T) {
+	input := `Text with <b>bold</b>, <script>alert(1)</script>, and ` + "`<code>`" + `.
+
+<div onclick="evil()">
+<span>allowed</span> <!-- a comment -->
+</div>`
+	want := `Text with <b>bold</b>, &lt;script>alert(1)&lt;/script>, and ` + "`<code>`" + `.
+
+&lt;div onclick="evil()">
+<span>allowed</span> <!-- a comment -->
+</div>`
+	got, err := processDocString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch in processDocString for raw HTML (-want, +got)\n:%s", diff)
 	}
 }
 
@@ -98,6 +189,84 @@ More text`
 	}
 }
 
+func TestPreserveOrderedListStart(t *testing.T) {
+	input := `Leading text
+
+3. an ordered list
+3. starting at three
+
+More text`
+	want := input
+	got, err := processDocString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch in processDocString for ordered lists (-want, +got)\n:%s", diff)
+	}
+}
+
+func TestPreserveBlockquotes(t *testing.T) {
+	input := `Leading text
+
+> A quote
+> with two lines.
+>
+> - and a list
+>   in the quote
+
+***
+
+- a list item
+  > with a quote
+
+More text`
+	want := input
+	got, err := processDocString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch in processDocString for block quotes (-want, +got)\n:%s", diff)
+	}
+}
+
+func TestPreserveGFM(t *testing.T) {
+	input := `Leading text
+
+| Name | Value |
+| :--- | ---: |
+| ~~old~~ | ` + "`a \\| b`" + ` |
+
+- [ ] a task
+- [x] a completed task
+
+Text with a footnote[^note].
+
+[^note]: The footnote
+    continues here.`
+	want := input
+	got, err := processDocString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch in processDocString for GFM extensions (-want, +got)\n:%s", diff)
+	}
+}
+
+func TestReferenceLinksInCodeBlocks(t *testing.T) {
+	input := "Uses [a link].\n\n```text\n[not-a-link]: inside a code block\n```\n\n[a link]: <https://example.com/with space> \"A title\""
+	want := "Uses [a link].\n\n```text\n[not-a-link]: inside a code block\n```\n\n[a link]: <https://example.com/with space> \"A title\""
+	got, err := processDocString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch in processDocString for reference links (-want, +got)\n:%s", diff)
+	}
+}
+
 func TestPreserveCodeBlocks(t *testing.T) {
 	input := `Leading text
 
@@ -174,3 +343,197 @@ More text`
 		t.Errorf("mismatch in processDocString for fenced code blocks (-want, +got)\n:%s", diff)
 	}
 }
+
+func TestParseCodeBlockInfo(t *testing.T) {
+	for _, test := range []struct {
+		info string
+		want codeBlockInfo
+	}{
+		{"", codeBlockInfo{Lang: "rust", IsRust: true}},
+		{"rust", codeBlockInfo{Lang: "rust", IsRust: true}},
+		{"no_run", codeBlockInfo{Lang: "rust", IsRust: true}},
+		{"rust,no_run", codeBlockInfo{Lang: "rust", IsRust: true}},
+		{"edition2021", codeBlockInfo{Lang: "rust", IsRust: true}},
+		{"ignore-windows", codeBlockInfo{Lang: "rust", IsRust: true}},
+		{"should_panic", codeBlockInfo{Lang: "rust", IsRust: true, Label: "This example panics."}},
+		{"compile_fail,edition2018", codeBlockInfo{Lang: "rust", IsRust: true, Label: "This example deliberately fails to compile."}},
+		{"ignore", codeBlockInfo{Lang: "rust", IsRust: true, Label: "This example is not tested."}},
+		{"text", codeBlockInfo{Lang: "text"}},
+		{"ignore, text", codeBlockInfo{Lang: "text"}},
+		{"norust", codeBlockInfo{Lang: "norust"}},
+	} {
+		got := parseCodeBlockInfo(test.info)
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("mismatch in parseCodeBlockInfo(%q) (-want, +got)\n:%s", test.info, diff)
+		}
+	}
+}
+
+func TestCodeBlockAttributes(t *testing.T) {
+	input := "```rust,should_panic\n# fn main() {\n#[derive(Debug)]\nstruct S;\n## not hidden\n#\npanic!();\n# }\n```\n\n```text\n# shown\n```"
+	want := "_This example panics._\n\n```rust\n#[derive(Debug)]\nstruct S;\n# not hidden\npanic!();\n```\n\n```text\n# shown\n```"
+	got, err := processDocString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch in processDocString for code block attributes (-want, +got)\n:%s", diff)
+	}
+}
+
+func TestCodeBlockFences(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		want  string
+	}{
+		{"~~~\n```\n~~~\n\nAfter.", "````rust\n```\n````\n\nAfter."},
+		{"`````text\n````\n```\n`````", "`````text\n````\n```\n`````"},
+		{"~~~text\nUse `code` and ``more``.\n~~~", "```text\nUse `code` and ``more``.\n```"},
+	} {
+		got, err := processDocString(test.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("mismatch in processDocString(%q) (-want, +got)\n:%s", test.input, diff)
+		}
+	}
+
+	input := "Summary.\n\n````text\n```\n\n# Not a heading\n````\n\n# Examples\n\nAn example."
+	want := docSections{
+		Summary:  "Summary.",
+		Remarks:  "````text\n```\n\n# Not a heading\n````",
+		Examples: []string{"An example."},
+	}
+	if diff := cmp.Diff(want, splitDocString(input)); diff != "" {
+		t.Errorf("mismatch in splitDocString(%q) (-want, +got)\n:%s", input, diff)
+	}
+}
+
+func TestDocSummary(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		want  string
+	}{
+		{"", ""},
+		{"A single sentence", "A single sentence"},
+		{"First sentence. Second sentence.", "First sentence."},
+		{"A sentence that\ncontinues in the next line. More text.", "A sentence that continues in the next line."},
+		{"First paragraph\n\nSecond paragraph.", "First paragraph"},
+		{"Required. The parent resource. Format: projects/{project}.", "Required. The parent resource."},
+		{"Output only. The name of the [Foo][google.cloud.Foo] resource.", "Output only. The name of the Foo resource."},
+		{"Returns a builder for [Client] and [Other][].", "Returns a builder for Client and Other."},
+		{"Keep [inline links](https://example.com) as-is.", "Keep [inline links](https://example.com) as-is."},
+		{"Escape a|b in tables.", `Escape a\|b in tables.`},
+		{"Use www.example.com for examples.", "Use www.example.com for examples."},
+		{"```rust\nfn f() {\n\n}\n```\n\nThe remarks.", "```rust fn f() { } ```"},
+		{"# Title\nThe text.\n\nMore text.", "# Title The text."},
+	} {
+		got := docSummary(test.input)
+		if got != test.want {
+			t.Errorf("docSummary(%q) = %q, want = %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestSplitDocString(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		want  docSections
+	}{
+		{
+			input: "Only a summary.",
+			want:  docSections{Summary: "Only a summary."},
+		},
+		{
+			input: "The summary\nin two lines.\n\nThe remarks.\n\nMore remarks.",
+			want:  docSections{Summary: "The summary\nin two lines.", Remarks: "The remarks.\n\nMore remarks."},
+		},
+		{
+			input: "```rust\nfn f() {\n\n}\n```\n\nThe remarks.",
+			want:  docSections{Summary: "```rust\nfn f() {\n\n}\n```", Remarks: "The remarks."},
+		},
+		{
+			input: "Uses [Foo].\n\n# Examples\n\n```bash\n# Not a heading\n```\n\n## Details\n\nMore example.\n\n# Errors\n\nReturns [Error].\n\n# Panics\n\nNever.\n\n# Other\n\nSection.\n\n[Foo]: xref:struct.Foo\n[Error]: xref:struct.Error",
+			want: docSections{
+				Summary:  "Uses [Foo].\n\n[Foo]: xref:struct.Foo",
+				Remarks:  "# Other\n\nSection.",
+				Examples: []string{"```bash\n# Not a heading\n```\n\n## Details\n\nMore example."},
+				Errors:   "Returns [Error].\n\n[Error]: xref:struct.Error",
+				Panics:   "Never.",
+			},
+		},
+		{
+			input: "Uses [Foo].\n\nSee [x][bar] and [Multi  Word][].\n\n[foo]: https://example.com/foo\n[BAR]: https://example.com/bar\n[multi word]: https://example.com/multi",
+			want: docSections{
+				Summary: "Uses [Foo].\n\n[foo]: https://example.com/foo",
+				Remarks: "See [x][bar] and [Multi  Word][].\n\n[BAR]: https://example.com/bar\n[multi word]: https://example.com/multi",
+			},
+		},
+		{
+			input: "Summary.\n\n#### <a id=\"examples-1\"></a>Examples\n\nAn example.",
+			want:  docSections{Summary: "Summary.", Examples: []string{"An example."}},
+		},
+	} {
+		got := splitDocString(test.input)
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("mismatch in splitDocString(%q) (-want, +got)\n:%s", test.input, diff)
+		}
+	}
+}
+
+func TestDocSummaryMatchesSplitDocString(t *testing.T) {
+	for _, input := range []string{
+		"A summary.\n\nThe remarks.",
+		"# Title\n\nThe text.",
+		"```rust\nfn f() {\n\n}\n```\n\nThe remarks.",
+		"~~~\ncode\n\nmore code\n~~~",
+	} {
+		split := splitDocString(input)
+		want := strings.Join(strings.Fields(split.Summary), " ")
+		if got := docSummary(input); !strings.HasPrefix(want, got) {
+			t.Errorf("docSummary(%q) = %q is not a prefix of the summary %q", input, got, want)
+		}
+	}
+}
+
+func TestHeadingLevels(t *testing.T) {
+	ctx := &docContext{HeadingBase: 3, anchors: map[string]bool{}}
+	input := "Leading text\n\n# Example\n\n## Details\n\n#### Deep"
+	want := "Leading text\n\n### <a id=\"example\"></a>Example\n\n#### <a id=\"details\"></a>Details\n\n###### <a id=\"deep\"></a>Deep"
+	got, err := processDocStringWithContext(input, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch in processDocStringWithContext for headings (-want, +got)\n:%s", diff)
+	}
+
+	// The anchors are unique across all the docstrings in the page.
+	input = "# Example\n\n# Example-1"
+	want = "### <a id=\"example-1\"></a>Example\n\n### <a id=\"example-1-1\"></a>Example-1"
+	got, err = processDocStringWithContext(input, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch in processDocStringWithContext for repeated headings (-want, +got)\n:%s", diff)
+	}
+}
+
+func TestHeadingAnchorLinks(t *testing.T) {
+	ctx := &docContext{HeadingBase: 3, anchors: map[string]bool{}}
+	if _, err := processDocStringWithContext("# Examples", ctx); err != nil {
+		t.Fatal(err)
+	}
+	input := "See [the examples](#examples), [other](#examples-other) and [ref].\n\n# Examples\n\n# Examples Other\n\n[ref]: #examples"
+	want := "See [the examples](#examples-1), [other](#examples-other) and [ref].\n\n" +
+		"### <a id=\"examples-1\"></a>Examples\n\n### <a id=\"examples-other\"></a>Examples Other\n\n[ref]: #examples-1"
+	got, err := processDocStringWithContext(input, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch in processDocStringWithContext for anchor links (-want, +got)\n:%s", diff)
+	}
+}
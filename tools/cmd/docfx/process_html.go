@@ -0,0 +1,115 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// htmlAlert is an HTML block that rustdoc users write to create callouts. We
+// convert these blocks into DocFX alerts, e.g. `> [!WARNING]`.
+type htmlAlert struct {
+	// Tag is the HTML tag that closes the alert, e.g. `div`.
+	Tag string
+	// Kind is the DocFX alert kind, e.g. `WARNING`.
+	Kind string
+	// Depth counts the nested elements with the same tag.
+	Depth int
+}
+
+var (
+	// See https://doc.rust-lang.org/rustdoc/how-to-write-documentation.html#adding-a-warning-block
+	warningStart = regexp.MustCompile(`^<div\s+class\s*=\s*["']warning["']\s*>`)
+	detailsStart = regexp.MustCompile(`^<details(\s+open)?\s*>`)
+	summaryLine  = regexp.MustCompile(`^<summary>(.*)</summary>$`)
+)
+
+// htmlAlertStart returns the alert started in `line`, if any, and the text
+// after the opening tag.
+func htmlAlertStart(line string) (*htmlAlert, string) {
+	line = strings.TrimSpace(line)
+	if loc := warningStart.FindStringIndex(line); loc != nil {
+		return &htmlAlert{Tag: "div", Kind: "WARNING"}, strings.TrimSpace(line[loc[1]:])
+	}
+	if loc := detailsStart.FindStringIndex(line); loc != nil {
+		return &htmlAlert{Tag: "details", Kind: "NOTE"}, strings.TrimSpace(line[loc[1]:])
+	}
+	return nil, ""
+}
+
+// htmlOpensTag returns true if `line` starts with an opening `tag`, e.g.
+// `<div>` or `<div class="x">`, but not `<divider>`.
+func htmlOpensTag(line, tag string) bool {
+	rest, ok := strings.CutPrefix(line, "<"+tag)
+	if !ok {
+		return false
+	}
+	return rest == "" || rest[0] == '>' || rest[0] == '/' || unicode.IsSpace(rune(rest[0]))
+}
+
+// htmlSummary returns the title in a `<summary>` line.
+func htmlSummary(line string) (string, bool) {
+	m := summaryLine.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return "", false
+	}
+	return strings.TrimSpace(m[1]), true
+}
+
+// allowedHTMLTags are the HTML tags we preserve in the documentation. Any
+// other tags are escaped, so they appear as text in the published pages.
+var allowedHTMLTags = []string{
+	"a", "abbr", "b", "blockquote", "br", "code", "dd", "del", "details", "div",
+	"dl", "dt", "em", "h1", "h2", "h3", "h4", "h5", "h6", "hr", "i", "img",
+	"ins", "kbd", "li", "mark", "ol", "p", "pre", "q", "s", "samp", "small",
+	"span", "strong", "sub", "summary", "sup", "table", "tbody", "td", "tfoot",
+	"th", "thead", "tr", "u", "ul", "var",
+}
+
+var (
+	htmlTag             = regexp.MustCompile(`^<(/?)([A-Za-z][A-Za-z0-9-]*)(\s[^>]*)?/?>$`)
+	htmlTagInText       = regexp.MustCompile(`<!--|<[!?][^>]*>?|</?[A-Za-z][^>]*>?`)
+	unsafeHTMLAttribute = regexp.MustCompile(`(?i)(\bon[a-z]+\s*=|\bstyle\s*=|javascript:)`)
+)
+
+// allowedHTML returns true if `tag`, e.g. `<b>` or `<a href="...">`, is safe
+// to include in the published pages.
+func allowedHTML(tag string) bool {
+	if strings.HasPrefix(tag, "<!--") {
+		// Comments are harmless.
+		return true
+	}
+	m := htmlTag.FindStringSubmatch(tag)
+	if m == nil {
+		return false
+	}
+	if !slices.Contains(allowedHTMLTags, strings.ToLower(m[2])) {
+		return false
+	}
+	return !unsafeHTMLAttribute.MatchString(m[3])
+}
+
+// sanitizeHTML escapes any HTML tags in `line` that are not allowed.
+func sanitizeHTML(line string) string {
+	return htmlTagInText.ReplaceAllStringFunc(line, func(tag string) string {
+		if allowedHTML(tag) {
+			return tag
+		}
+		return "&lt;" + tag[1:]
+	})
+}
@@ -0,0 +1,64 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "testing"
+
+func TestAllowedHTML(t *testing.T) {
+	for _, test := range []struct {
+		tag  string
+		want bool
+	}{
+		{"<b>", true},
+		{"</b>", true},
+		{"<br/>", true},
+		{"<br />", true},
+		{`<a href="https://example.com">`, true},
+		{"<!-- comment -->", true},
+		{`<div class="warning">`, true},
+		{"<script>", false},
+		{"</script>", false},
+		{"<iframe>", false},
+		{"<T>", false},
+		{`<img src="x" onerror="evil()">`, false},
+		{`<a href="javascript:evil()">`, false},
+		{`<span style="display:none">`, false},
+		{"<?php", false},
+		{"<!DOCTYPE html>", false},
+	} {
+		if got := allowedHTML(test.tag); got != test.want {
+			t.Errorf("allowedHTML(%q) = %v, want = %v", test.tag, got, test.want)
+		}
+	}
+}
+
+func TestHTMLOpensTag(t *testing.T) {
+	for _, test := range []struct {
+		line string
+		want bool
+	}{
+		{"<div>", true},
+		{`<div class="x">`, true},
+		{"<div", true},
+		{"<divider>", false},
+		{"<details-x>", false},
+		{"</div>", false},
+		{"text <div>", false},
+	} {
+		if got := htmlOpensTag(test.line, "div"); got != test.want {
+			t.Errorf("htmlOpensTag(%q, div) = %v, want = %v", test.line, got, test.want)
+		}
+	}
+}
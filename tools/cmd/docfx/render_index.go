@@ -17,10 +17,12 @@ package main
 import (
 	"embed"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"os"
 	fspath "path"
 	"path/filepath"
+	"strings"
 
 	"github.com/cbroglie/mustache"
 )
@@ -45,14 +47,73 @@ type indexEntry struct {
 	Version      string
 	APIShortName string
 	Product      string
+	ReleaseLevel string
+	ProductDocs  string
+	ClientDocs   string
+	IssueTracker string
 	Last         bool
 }
 
 // repoMetadata simplifies parsing of `.repo-metadata.json` files.
 type repoMetadata struct {
-	ApiId        string `json:"api_id"`
-	ApiShortName string `json:"api_shortname"`
-	NamePretty   string `json:"name_pretty"`
+	ApiId                string `json:"api_id"`
+	ApiShortName         string `json:"api_shortname"`
+	NamePretty           string `json:"name_pretty"`
+	LibraryType          string `json:"library_type"`
+	ProductDocumentation string `json:"product_documentation"`
+	ReleaseLevel         string `json:"release_level"`
+	IssueTracker         string `json:"issue_tracker"`
+	ClientDocumentation  string `json:"client_documentation"`
+}
+
+// IsPreview returns true if the crate is not generally available.
+func (m *repoMetadata) IsPreview() bool {
+	return m.ReleaseLevel != "" && m.ReleaseLevel != "stable"
+}
+
+// previewBadge is shown at the top of the pages for preview crates.
+const previewBadge = `> [!IMPORTANT]
+> **Preview**: this crate is not generally available. Its APIs may change in
+> backwards-incompatible ways.`
+
+// releaseBadge returns the badge describing the release level of the crate,
+// if any.
+func (m *repoMetadata) releaseBadge() string {
+	if m == nil || !m.IsPreview() {
+		return ""
+	}
+	return previewBadge
+}
+
+// links formats the product, client and issue tracker links as a markdown
+// list.
+func (m *repoMetadata) links() string {
+	if m == nil {
+		return ""
+	}
+	var lines []string
+	add := func(name, url string) {
+		if url != "" {
+			lines = append(lines, fmt.Sprintf("- %s: <%s>", name, url))
+		}
+	}
+	add("Product documentation", m.ProductDocumentation)
+	add("Client documentation", m.ClientDocumentation)
+	add("Issue tracker", m.IssueTracker)
+	return strings.Join(lines, "\n")
+}
+
+// readRepoMetadata loads the `.repo-metadata.json` file in `location`.
+func readRepoMetadata(location string) (*repoMetadata, error) {
+	contents, err := os.ReadFile(fspath.Join(location, ".repo-metadata.json"))
+	if err != nil {
+		return nil, err
+	}
+	metadata := &repoMetadata{}
+	if err := json.Unmarshal(contents, metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
 }
 
 // renderIndex generates an index file in a format suitable for cloud.google.com
@@ -63,20 +124,20 @@ func renderIndex(crates []crate, outDir string) error {
 	}
 	context := &index{}
 	for _, c := range crates {
-		metadata := repoMetadata{}
-		contents, err := os.ReadFile(fspath.Join(c.Location, ".repo-metadata.json"))
+		metadata, err := readRepoMetadata(c.Location)
 		if err != nil {
 			slog.Warn("cannot read repo metadata", "location", c.Location, "package", c.Name)
 			continue
 		}
-		if err := json.Unmarshal(contents, &metadata); err != nil {
-			continue
-		}
 		context.Entries = append(context.Entries, &indexEntry{
 			PkgName:      c.Name,
 			Version:      c.Version,
 			APIShortName: metadata.ApiShortName,
 			Product:      metadata.NamePretty,
+			ReleaseLevel: metadata.ReleaseLevel,
+			ProductDocs:  metadata.ProductDocumentation,
+			ClientDocs:   metadata.ClientDocumentation,
+			IssueTracker: metadata.IssueTracker,
 		})
 	}
 	if len(context.Entries) != 0 {
@@ -29,6 +29,10 @@ type librariesEntry struct {
 	DocsUrl      string
 	APIShortName string
 	Product      string
+	ReleaseLevel string
+	ProductDocs  string
+	ClientDocs   string
+	IssueTracker string
 }
 
 type librariesIndex = map[string][]librariesEntry
@@ -70,6 +74,10 @@ func TestRenderIndex(t *testing.T) {
 			DocsUrl:      "https://docs.rs/google-cloud-storage/latest",
 			APIShortName: "storage",
 			Product:      "Cloud Storage API",
+			ReleaseLevel: "stable",
+			ProductDocs:  "https://cloud.google.com/storage/docs",
+			ClientDocs:   "https://docs.rs/google-cloud-storage/latest",
+			IssueTracker: "https://issuetracker.google.com/savedsearches/559782",
 		},
 	}
 	if diff := cmp.Diff(want, got); diff != "" {
@@ -45,3 +45,37 @@ func TestRenderMetadata(t *testing.T) {
 		t.Errorf("expected line with `name: ...` in: %q", text)
 	}
 }
+
+func TestRenderMetadataWithRepoMetadata(t *testing.T) {
+	input := &crate{
+		Name:    "test-only",
+		Version: "0.0.0-test",
+		Root:    1234567890,
+		Index: map[string]item{
+			"1234567890": {
+				Name: "root-name",
+			},
+		},
+		Metadata: &repoMetadata{
+			ProductDocumentation: "https://cloud.google.com/test",
+			IssueTracker:         "https://issuetracker.google.com/test",
+		},
+	}
+	outDir := t.TempDir()
+	if err := renderMetadata(input, outDir); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := os.ReadFile(ospath.Join(outDir, "docs.metadata"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(contents)
+	for _, want := range []string{
+		`product_page: "https://cloud.google.com/test"`,
+		`issue_tracker: "https://issuetracker.google.com/test"`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected line with %q in: %q", want, text)
+		}
+	}
+}
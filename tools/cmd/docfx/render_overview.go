@@ -0,0 +1,148 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/cbroglie/mustache"
+)
+
+// overviewFile is the name of the conceptual overview page for each crate.
+const overviewFile = "overview.md"
+
+// overviewHeadingBase is the level for the top-level headings in the crate
+// docs. The page title is the only level 1 heading.
+const overviewHeadingBase = 2
+
+// docfxOverview is a context for the `overview.md` mustache template.
+type docfxOverview struct {
+	Title        string
+	Crate        string
+	Version      string
+	Product      string
+	ReleaseLevel string
+	// Badge is shown for crates that are not generally available.
+	Badge string
+	// Links is a list with the product, client and issue tracker links.
+	Links string
+	// Docs contains the crate-level documentation.
+	Docs string
+	// Readme contains the sections of the README not already in Docs.
+	Readme string
+	// docs is used to process the crate docs and the README.
+	docs *docContext
+}
+
+// HasDetails returns true if there is any metadata to show, the mustache
+// templates use this to avoid empty sections.
+func (o *docfxOverview) HasDetails() bool {
+	return o.Product != "" || o.ReleaseLevel != "" || o.Links != ""
+}
+
+func newDocfxOverview(c *crate) (*docfxOverview, error) {
+	rootId := idToString(c.Root)
+	o := &docfxOverview{
+		Title:   c.Name,
+		Crate:   c.Name,
+		Version: c.Version,
+	}
+	if m := c.Metadata; m != nil {
+		if m.NamePretty != "" {
+			o.Title = m.NamePretty
+		}
+		o.Product = m.NamePretty
+		o.ReleaseLevel = m.ReleaseLevel
+		o.Badge = m.releaseBadge()
+		o.Links = m.links()
+	}
+
+	ctx := c.newDocContext(rootId)
+	ctx.HeadingBase = overviewHeadingBase
+	o.docs = ctx
+	docs, err := c.getDocString(ctx, rootId)
+	if err != nil {
+		return nil, err
+	}
+	o.Docs = docs
+
+	readme, err := os.ReadFile(filepath.Join(c.Location, "README.md"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return o, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	contents := removeDuplicateParagraphs(removeTitle(string(readme)), c.Index[rootId].Docs)
+	if contents != "" {
+		// The README sections start at level 2, its title was removed.
+		ctx.HeadingBase = 1
+		if o.Readme, err = processDocStringWithContext(contents, ctx); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// removeTitle removes the level 1 heading at the start of a README file. The
+// overview has its own title.
+func removeTitle(contents string) string {
+	contents = strings.TrimSpace(contents)
+	if first, rest, _ := strings.Cut(contents, "\n"); strings.HasPrefix(first, "# ") {
+		return strings.TrimSpace(rest)
+	}
+	return contents
+}
+
+// removeDuplicateParagraphs removes the paragraphs in `contents` that also
+// appear in `docs`. The crate-level docs and the README often share some
+// paragraphs, or the crate-level docs may include the README.
+//
+// The comparison ignores differences in whitespace, as the line breaks are
+// often different.
+func removeDuplicateParagraphs(contents, docs string) string {
+	normalize := func(s string) string { return strings.Join(strings.Fields(s), " ") }
+	normalized := normalize(docs)
+	paragraphs := strings.Split(contents, "\n\n")
+	paragraphs = slices.DeleteFunc(paragraphs, func(p string) bool {
+		p = normalize(p)
+		return p != "" && strings.Contains(normalized, p)
+	})
+	return strings.TrimSpace(strings.Join(paragraphs, "\n\n"))
+}
+
+func renderOverview(c *crate, outDir string) error {
+	o, err := newDocfxOverview(c)
+	if err != nil {
+		return err
+	}
+	contents, err := templatesProvider("overview.md.mustache")
+	if err != nil {
+		return err
+	}
+	output, err := mustache.RenderPartials(contents, &mustacheProvider{}, o)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outDir, overviewFile), []byte(output), 0644); err != nil {
+		return err
+	}
+	return copyAssets(o.docs.assets, outDir)
+}
@@ -0,0 +1,233 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderOverview(t *testing.T) {
+	location := t.TempDir()
+	readme := `# Google Cloud Client Libraries for Rust - Test
+
+The crate docs.
+
+## More information
+
+Some details only in the README.
+`
+	if err := os.WriteFile(filepath.Join(location, "README.md"), []byte(readme), 0644); err != nil {
+		t.Fatal(err)
+	}
+	input := &crate{
+		Name:     "test-only",
+		Version:  "1.2.3",
+		Root:     1234567890,
+		Location: location,
+		Metadata: &repoMetadata{
+			NamePretty:           "Test API",
+			ProductDocumentation: "https://cloud.google.com/test",
+			ReleaseLevel:         "stable",
+			IssueTracker:         "https://github.com/googleapis/google-cloud-rust/issues",
+		},
+		Index: map[string]item{
+			"1234567890": {
+				Name: "test_only",
+				Docs: "The crate docs.\n\n# Example\n\nSome example.",
+			},
+		},
+	}
+	outDir := t.TempDir()
+	if err := renderOverview(input, outDir); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := os.ReadFile(filepath.Join(outDir, overviewFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Split(string(contents), "\n")
+	want := []string{
+		"# Test API",
+		"",
+		"- Product: Test API",
+		"- Release level: stable",
+		"- Product documentation: <https://cloud.google.com/test>",
+		"- Issue tracker: <https://github.com/googleapis/google-cloud-rust/issues>",
+		"",
+		"The crate docs.",
+		"",
+		`## <a id="example"></a>Example`,
+		"",
+		"Some example.",
+		"",
+		"## Installation",
+		"",
+		"Add the crate to the dependencies in your `Cargo.toml` file:",
+		"",
+		"```toml",
+		"[dependencies]",
+		`test-only = "1.2.3"`,
+		"```",
+		"",
+		`## <a id="more-information"></a>More information`,
+		"",
+		"Some details only in the README.",
+		"",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatched overview (-want, +got):\n%s", diff)
+	}
+}
+
+func TestRenderOverviewWithoutReadme(t *testing.T) {
+	input := &crate{
+		Name:     "test-only",
+		Version:  "1.2.3",
+		Root:     1234567890,
+		Location: t.TempDir(),
+		Index: map[string]item{
+			"1234567890": {Name: "test_only"},
+		},
+	}
+	outDir := t.TempDir()
+	if err := renderOverview(input, outDir); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := os.ReadFile(filepath.Join(outDir, overviewFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Split(string(contents), "\n")
+	want := []string{
+		"# test-only",
+		"",
+		"## Installation",
+		"",
+		"Add the crate to the dependencies in your `Cargo.toml` file:",
+		"",
+		"```toml",
+		"[dependencies]",
+		`test-only = "1.2.3"`,
+		"```",
+		"",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatched overview (-want, +got):\n%s", diff)
+	}
+}
+
+func TestRenderOverviewAssets(t *testing.T) {
+	location := t.TempDir()
+	files := map[string]string{
+		"README.md":       "# Test\n\n![logo](logo.png)\n",
+		"logo.png":        "logo",
+		"images/arch.png": "arch",
+	}
+	for name, contents := range files {
+		path := filepath.Join(location, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	input := &crate{
+		Name:     "test-only",
+		Version:  "1.2.3",
+		Root:     1234567890,
+		Location: location,
+		Index: map[string]item{
+			"1234567890": {
+				Name: "test_only",
+				Docs: "The crate docs.\n\n![architecture](images/arch.png)",
+			},
+		},
+	}
+	outDir := t.TempDir()
+	if err := renderOverview(input, outDir); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := os.ReadFile(filepath.Join(outDir, overviewFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"![logo](assets/logo.png)", "![architecture](assets/images/arch.png)"} {
+		if !strings.Contains(string(contents), want) {
+			t.Errorf("expected %q in the overview, got=%s", want, contents)
+		}
+	}
+	for _, name := range []string{"assets/logo.png", "assets/images/arch.png"} {
+		if _, err := os.Stat(filepath.Join(outDir, name)); err != nil {
+			t.Errorf("missing asset %s in output directory: %v", name, err)
+		}
+	}
+}
+
+func TestRemoveDuplicateParagraphs(t *testing.T) {
+	docs := "First paragraph\nwith a line break.\n\nSecond paragraph."
+	contents := "First paragraph with a line break.\n\nOnly in the README.\n\nSecond paragraph."
+	got := removeDuplicateParagraphs(contents, docs)
+	want := "Only in the README."
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestRenderOverviewPreview(t *testing.T) {
+	input := &crate{
+		Name:     "test-only",
+		Version:  "0.1.0",
+		Root:     1234567890,
+		Location: t.TempDir(),
+		Metadata: &repoMetadata{
+			NamePretty:          "Test API",
+			ReleaseLevel:        "preview",
+			ClientDocumentation: "https://docs.rs/test-only/latest",
+		},
+		Index: map[string]item{
+			"1234567890": {Name: "test_only"},
+		},
+	}
+	outDir := t.TempDir()
+	if err := renderOverview(input, outDir); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := os.ReadFile(filepath.Join(outDir, overviewFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Split(string(contents), "\n")[:10]
+	want := []string{
+		"# Test API",
+		"",
+		"> [!IMPORTANT]",
+		"> **Preview**: this crate is not generally available. Its APIs may change in",
+		"> backwards-incompatible ways.",
+		"",
+		"- Product: Test API",
+		"- Release level: preview",
+		"- Client documentation: <https://docs.rs/test-only/latest>",
+		"",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatched overview (-want, +got):\n%s", diff)
+	}
+}
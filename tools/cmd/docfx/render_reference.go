@@ -44,5 +44,5 @@ func renderReference(crate *crate, id, outDir string) error {
 	if err := os.WriteFile(outputFile, []byte(output), 0644); err != nil {
 		return err
 	}
-	return nil
+	return copyAssets(reference.docs.assets, outDir)
 }
@@ -15,11 +15,11 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	fspath "path"
 	"slices"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -49,11 +49,63 @@ func TestRenderReferenceCrate(t *testing.T) {
 	got := lines[idx+1 : idx+4]
 	want := []string{
 		"    Google Cloud Client Libraries for Rust - Public Certificate Authority API",
-		"    ",
+		"  remarks: |",
 		"    This crate contains traits, types, and functions to interact with Public Certificate Authority API",
 	}
 	if diff := cmp.Diff(want, got); diff != "" {
-		t.Errorf("mismatched summary lines in generated YAML (-want +got):\n%s", diff)
+		t.Errorf("mismatched remarks lines in generated YAML (-want +got):\n%s", diff)
+	}
+}
+
+func TestRenderReferenceCratePreview(t *testing.T) {
+	input, err := testDataPublicCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	input.Metadata = &repoMetadata{
+		ReleaseLevel:         "preview",
+		ProductDocumentation: "https://cloud.google.com/certificate-manager/",
+		IssueTracker:         "https://cloud.google.com/certificate-manager/docs/getting-support",
+	}
+	outDir := t.TempDir()
+	wantUid := "crate.google_cloud_security_publicca_v1"
+	id := findIdByUid(t, input, wantUid)
+	if err := renderReference(input, id, outDir); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := os.ReadFile(fspath.Join(outDir, fmt.Sprintf("%s.yml", wantUid)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(contents), "\n")
+	idx := slices.IndexFunc(lines, func(a string) bool { return strings.Contains(a, "summary: |") })
+	if idx == -1 {
+		t.Fatalf("missing `summary: |` line in output YAML %s", contents)
+	}
+	if strings.Contains(lines[idx+1], "[!IMPORTANT]") {
+		t.Errorf("the badge should not be in the summary, got=%q", lines[idx+1])
+	}
+	idx = slices.IndexFunc(lines, func(a string) bool { return strings.Contains(a, "remarks: |") })
+	if idx == -1 {
+		t.Fatalf("missing `remarks: |` line in output YAML %s", contents)
+	}
+	got := lines[idx+1 : idx+5]
+	want := []string{
+		"    > [!IMPORTANT]",
+		"    > **Preview**: this crate is not generally available. Its APIs may change in",
+		"    > backwards-incompatible ways.",
+		"    ",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatched remarks lines in generated YAML (-want +got):\n%s", diff)
+	}
+	for _, want := range []string{
+		"    - Product documentation: <https://cloud.google.com/certificate-manager/>",
+		"    - Issue tracker: <https://cloud.google.com/certificate-manager/docs/getting-support>",
+	} {
+		if !slices.Contains(lines, want) {
+			t.Errorf("missing line %q in output YAML %s", want, contents)
+		}
 	}
 }
 
@@ -89,7 +141,7 @@ func TestRenderReferenceFunction(t *testing.T) {
 		"    Returns a builder for [PublicCertificateAuthorityService].",
 	}
 	if diff := cmp.Diff(want, lines[idx+1:idx+1+len(want)]); diff != "" {
-		t.Errorf("mismatched summary lines in generated YAML (-want +got):\n%s", diff)
+		t.Errorf("mismatched remarks lines in generated YAML (-want +got):\n%s", diff)
 	}
 }
 
@@ -123,7 +175,7 @@ func TestRenderReferenceFields(t *testing.T) {
 		"    when the ExternalAccountKey is created",
 	}
 	if diff := cmp.Diff(want, lines[idx+1:idx+1+len(want)]); diff != "" {
-		t.Errorf("mismatched summary lines in generated YAML (-want +got):\n%s", diff)
+		t.Errorf("mismatched remarks lines in generated YAML (-want +got):\n%s", diff)
 	}
 }
 
@@ -157,7 +209,7 @@ func TestRenderReferenceTypeAlias(t *testing.T) {
 		"    ```",
 	}
 	if diff := cmp.Diff(want, lines[idx+1:idx+1+len(want)]); diff != "" {
-		t.Errorf("mismatched summary lines in generated YAML (-want +got):\n%s", diff)
+		t.Errorf("mismatched remarks lines in generated YAML (-want +got):\n%s", diff)
 	}
 }
 
@@ -191,7 +243,7 @@ func TestRenderReferenceTypeAliasPrimitive(t *testing.T) {
 		"    ```",
 	}
 	if diff := cmp.Diff(want, lines[idx+1:idx+1+len(want)]); diff != "" {
-		t.Errorf("mismatched summary lines in generated YAML (-want +got):\n%s", diff)
+		t.Errorf("mismatched remarks lines in generated YAML (-want +got):\n%s", diff)
 	}
 }
 
@@ -214,7 +266,92 @@ func testDataPublicCA() (*crate, error) {
 		return nil, err
 	}
 	crate := new(crate)
-	// Our parser cannot handle certain attributes, we ignore errors for now
-	_ = json.Unmarshal(contents, &crate)
+	if err := unmarshalRustdoc(crate, contents); err != nil {
+		return nil, err
+	}
 	return crate, nil
 }
+
+func TestModuleSummaryTables(t *testing.T) {
+	input, err := testDataPublicCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := findIdByUid(t, input, "module.google_cloud_security_publicca_v1.builder.public_certificate_authority_service")
+	got := input.moduleSummaryTables(id)
+	want := strings.Join([]string{
+		"**Structs**",
+		"",
+		"| Name | Description |",
+		"| --- | --- |",
+		"| [CreateExternalAccountKey](xref:struct.google_cloud_security_publicca_v1.builder.public_certificate_authority_service.CreateExternalAccountKey) | The request builder for PublicCertificateAuthorityService::create_external_account_key calls. |",
+		"",
+		"**Type Aliases**",
+		"",
+		"| Name | Description |",
+		"| --- | --- |",
+		"| [ClientBuilder](xref:typealias.google_cloud_security_publicca_v1.builder.public_certificate_authority_service.ClientBuilder) | A builder for PublicCertificateAuthorityService. |",
+	}, "\n")
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch in module summary tables (-want, +got)\n:%s", diff)
+	}
+}
+
+func TestRenderReferenceFreeFunction(t *testing.T) {
+	input, err := testDataPublicCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootUid := "crate.google_cloud_security_publicca_v1"
+	rootId := findIdByUid(t, input, rootUid)
+	addTestFreeFunction(input, rootId, "10000", "helper")
+	outDir := t.TempDir()
+	if err := renderReference(input, rootId, outDir); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := os.ReadFile(fspath.Join(outDir, fmt.Sprintf("%s.yml", rootUid)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(contents), "\n")
+	functionStart := "- uid: function.google_cloud_security_publicca_v1.helper"
+	idx := slices.Index(lines, functionStart)
+	if idx == -1 {
+		t.Fatalf("missing %s in output YAML %s", functionStart, contents)
+	}
+	want := []string{
+		functionStart,
+		"  name: helper",
+		"  langs:",
+		"  - rust",
+		"  type: function",
+		"  summary: |",
+		"    ```rust",
+		"    fn helper()",
+		"    ```",
+		"    ",
+		"    A free function.",
+	}
+	if diff := cmp.Diff(want, lines[idx:idx+len(want)]); diff != "" {
+		t.Errorf("mismatched function lines in generated YAML (-want +got):\n%s", diff)
+	}
+
+	toc, err := computeTOC(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, f := range toc.Functions {
+		got = append(got, f.Uid)
+	}
+	if diff := cmp.Diff([]string{"function.google_cloud_security_publicca_v1.helper"}, got); diff != "" {
+		t.Errorf("mismatched functions in TOC (-want +got):\n%s", diff)
+	}
+}
+
+func addTestFreeFunction(c *crate, moduleId, id, name string) {
+	c.Index[id] = item{Name: name, Docs: "A free function.", Inner: itemEnum{Function: &function{}}}
+	c.Paths[id] = itemSummary{Kind: "function", Path: append(slices.Clone(c.Paths[moduleId].Path), name)}
+	n, _ := strconv.ParseUint(id, 10, 32)
+	c.Index[moduleId].Inner.Module.Items = append(c.Index[moduleId].Inner.Module.Items, uint32(n))
+}
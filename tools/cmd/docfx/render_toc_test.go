@@ -224,6 +224,8 @@ func TestRenderTocNestedModules(t *testing.T) {
 		"- uid: crate.google_cloud_security_publicca_v1",
 		"  name: google_cloud_security_publicca_v1",
 		"  items:",
+		"  - href: overview.md",
+		"    name: Overview",
 		"  - name: Modules",
 		"    items:",
 		"    - uid: module.google_cloud_security_publicca_v1.builder",
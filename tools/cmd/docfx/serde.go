@@ -0,0 +1,320 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// serdeAttributes holds the `#[serde(...)]` attributes of an item.
+//
+// See https://serde.rs/attributes.html for their meaning. Only the attributes
+// that change the wire representation of fields and variants are parsed.
+type serdeAttributes struct {
+	Rename            string
+	RenameAll         string
+	Aliases           []string
+	Skip              bool
+	SkipSerializing   bool
+	SkipSerializingIf string
+	Flatten           bool
+}
+
+// parseSerdeAttributes parses the `#[serde(...)]` attributes in `attrs`.
+func parseSerdeAttributes(attrs []string) serdeAttributes {
+	var result serdeAttributes
+	for _, attr := range attrs {
+		body, ok := strings.CutPrefix(strings.TrimSpace(attr), "#[serde(")
+		if !ok {
+			continue
+		}
+		body, ok = strings.CutSuffix(body, ")]")
+		if !ok {
+			continue
+		}
+		for _, arg := range splitSerdeArgs(body) {
+			key, value, _ := strings.Cut(arg, "=")
+			key = strings.TrimSpace(key)
+			value = serdeValue(value)
+			// `rename(serialize = "a", deserialize = "b")` and `rename_all(...)`
+			// use different names in each direction. We document the
+			// serialized name.
+			if name, inner, ok := strings.Cut(arg, "("); ok && !strings.Contains(name, "=") {
+				key = strings.TrimSpace(name)
+				value = ""
+				for _, a := range splitSerdeArgs(strings.TrimSuffix(inner, ")")) {
+					if k, v, _ := strings.Cut(a, "="); strings.TrimSpace(k) == "serialize" {
+						value = serdeValue(v)
+					}
+				}
+			}
+			switch key {
+			case "rename":
+				result.Rename = value
+			case "rename_all":
+				result.RenameAll = value
+			case "alias":
+				result.Aliases = append(result.Aliases, value)
+			case "skip":
+				result.Skip = true
+			case "skip_serializing":
+				result.SkipSerializing = true
+			case "skip_serializing_if":
+				result.SkipSerializingIf = value
+			case "flatten":
+				result.Flatten = true
+			}
+		}
+	}
+	return result
+}
+
+// splitSerdeArgs splits the arguments in a `serde(...)` attribute, ignoring
+// commas in strings and nested parenthesis.
+func splitSerdeArgs(s string) []string {
+	var args []string
+	depth := 0
+	quoted := false
+	start := 0
+	for i, r := range s {
+		switch {
+		case r == '"' && (i == 0 || s[i-1] != '\\'):
+			quoted = !quoted
+		case quoted:
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			args = append(args, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" {
+		args = append(args, last)
+	}
+	return args
+}
+
+func serdeValue(v string) string {
+	return strings.Trim(strings.TrimSpace(v), `"`)
+}
+
+// wireName returns the name used in the serialized form of a field or
+// variant, and true if the name differs from the Rust name or is explicitly
+// set.
+//
+// Returns false if the item has no serde attributes affecting its name.
+func wireName(name string, item, container serdeAttributes, isVariant bool) (string, bool) {
+	if item.Rename != "" {
+		return item.Rename, true
+	}
+	if container.RenameAll == "" {
+		return "", false
+	}
+	return applyRenameRule(container.RenameAll, name, isVariant), true
+}
+
+// applyRenameRule converts a field (in `snake_case`) or a variant (in
+// `PascalCase`) name using one of the `rename_all` rules.
+func applyRenameRule(rule, name string, isVariant bool) string {
+	// Split the name into lowercase words.
+	var words []string
+	if isVariant {
+		var current []rune
+		for _, r := range name {
+			if unicode.IsUpper(r) && len(current) != 0 {
+				words = append(words, string(current))
+				current = nil
+			}
+			current = append(current, unicode.ToLower(r))
+		}
+		if len(current) != 0 {
+			words = append(words, string(current))
+		}
+	} else {
+		words = strings.Split(name, "_")
+	}
+	title := func(w string) string {
+		if w == "" {
+			return w
+		}
+		return strings.ToUpper(w[:1]) + w[1:]
+	}
+	switch rule {
+	case "lowercase":
+		return strings.ToLower(strings.Join(words, ""))
+	case "UPPERCASE":
+		return strings.ToUpper(strings.Join(words, ""))
+	case "PascalCase":
+		var b strings.Builder
+		for _, w := range words {
+			b.WriteString(title(w))
+		}
+		return b.String()
+	case "camelCase":
+		var b strings.Builder
+		for i, w := range words {
+			if i == 0 {
+				b.WriteString(w)
+			} else {
+				b.WriteString(title(w))
+			}
+		}
+		return b.String()
+	case "snake_case":
+		return strings.Join(words, "_")
+	case "SCREAMING_SNAKE_CASE":
+		return strings.ToUpper(strings.Join(words, "_"))
+	case "kebab-case":
+		return strings.Join(words, "-")
+	case "SCREAMING-KEBAB-CASE":
+		return strings.ToUpper(strings.Join(words, "-"))
+	}
+	return name
+}
+
+// serdeNotes formats the wire name and serialization notes for a field or
+// variant.
+func serdeNotes(name string, item, container serdeAttributes, isVariant bool) string {
+	var lines []string
+	if wire, ok := wireName(name, item, container, isVariant); ok {
+		line := fmt.Sprintf("Wire name: `%s`", wire)
+		if len(item.Aliases) != 0 {
+			line = fmt.Sprintf("%s (also accepts `%s`)", line, strings.Join(item.Aliases, "`, `"))
+		}
+		lines = append(lines, line)
+	} else if len(item.Aliases) != 0 {
+		lines = append(lines, fmt.Sprintf("Also accepts `%s`", strings.Join(item.Aliases, "`, `")))
+	}
+	switch {
+	case item.Skip:
+		lines = append(lines, "Skipped during serialization and deserialization.")
+	case item.SkipSerializing:
+		lines = append(lines, "Skipped during serialization.")
+	case item.SkipSerializingIf != "":
+		lines = append(lines, fmt.Sprintf("Skipped during serialization if `%s` returns `true`.", item.SkipSerializingIf))
+	}
+	if item.Flatten {
+		lines = append(lines, "The fields are flattened into the containing object.")
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n\n")
+}
+
+// addSerdeNotes prepends the serialization notes for a field or variant to the
+// remarks of `item`. The container attributes are read from `containerId`.
+func (c *crate) addSerdeNotes(item *docfxItem, id, containerId string, isVariant bool) {
+	attrs := parseSerdeAttributes(c.Index[id].Attrs)
+	if attrs.Rename == "" {
+		attrs.Rename = c.generatedWireName(id, containerId, isVariant)
+	}
+	notes := serdeNotes(
+		c.getName(id),
+		attrs,
+		parseSerdeAttributes(c.Index[containerId].Attrs),
+		isVariant)
+	item.prependRemarks(notes)
+}
+
+// generatedModelFile is the serializer for the types in the `model` module
+// of the generated crates. The generated types do not use serde attributes.
+const generatedModelFile = "src/model/serialize.rs"
+
+var (
+	// Matches the start of the serializer for a type, e.g.
+	// `impl serde::ser::Serialize for super::secret::Rotation {`.
+	serializeImpl = regexp.MustCompile(`(?m)^\s*impl serde::ser::Serialize for super::([\w:]+) \{`)
+	// Matches the serialization of a field, e.g.
+	// `state.serialize_entry("keyId", &self.key_id)?;`.
+	serializeField = regexp.MustCompile(`serialize_entry\(\s*"([^"]+)",\s*&(?:__With\(&)?self\.(?:r#)?(\w+)\b`)
+	// Matches the serialization of a oneof branch, through its accessor, e.g.
+	// `if let Some(value) = self.expire_time() { ... state.serialize_entry("expireTime", value)?;`.
+	serializeOneOf = regexp.MustCompile(`(?s)if let Some\(value\) = self\.(?:r#)?(\w+)\(\) \{.*?serialize_entry\(\s*"([^"]+)",\s*(?:&__With\()?value\b`)
+)
+
+// readWireNames returns the proto JSON names of the fields in the generated
+// model types, as serialized by the generated code in `location`. Returns nil
+// if the crate has no generated serializer.
+//
+// The keys are the field paths relative to the `model` module, e.g.
+// `Secret::create_time`. The keys for oneof branches use the module with the
+// oneof enum, e.g. `secret::expire_time` for `secret::Expiration::ExpireTime`.
+// The enums serialize their values as numbers and have no wire names.
+func readWireNames(location string) (map[string]string, error) {
+	contents, err := os.ReadFile(filepath.Join(location, filepath.FromSlash(generatedModelFile)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	source := string(contents)
+	names := map[string]string{}
+	impls := serializeImpl.FindAllStringSubmatchIndex(source, -1)
+	for i, m := range impls {
+		end := len(source)
+		if i+1 < len(impls) {
+			end = impls[i+1][0]
+		}
+		body := source[m[1]:end]
+		typeName := source[m[2]:m[3]]
+		for _, f := range serializeField.FindAllStringSubmatch(body, -1) {
+			names[typeName+"::"+f[2]] = f[1]
+		}
+		module := modulePath(typeName)
+		for _, f := range serializeOneOf.FindAllStringSubmatch(body, -1) {
+			names[module+"::"+f[1]] = f[2]
+		}
+	}
+	return names, nil
+}
+
+// modulePath returns the path of the module generated for the nested types of
+// `typeName`, e.g. `secret_version` for `SecretVersion`.
+func modulePath(typeName string) string {
+	segments := strings.Split(typeName, "::")
+	last := len(segments) - 1
+	segments[last] = applyRenameRule("snake_case", segments[last], true)
+	return strings.Join(segments, "::")
+}
+
+// generatedWireName returns the proto JSON name of a field or oneof branch in
+// the generated model types. Returns the empty string for other items.
+func (c *crate) generatedWireName(id, containerId string, isVariant bool) string {
+	if len(c.WireNames) == 0 {
+		return ""
+	}
+	// The paths start with the crate name and the `model` module.
+	path := c.Paths[containerId].Path
+	if len(path) < 3 || path[1] != "model" {
+		return ""
+	}
+	name := strings.TrimPrefix(c.getName(id), "r#")
+	if isVariant {
+		module := strings.Join(path[2:len(path)-1], "::")
+		return c.WireNames[module+"::"+applyRenameRule("snake_case", name, true)]
+	}
+	return c.WireNames[strings.Join(path[2:], "::")+"::"+name]
+}
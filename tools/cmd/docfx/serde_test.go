@@ -0,0 +1,205 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseSerdeAttributes(t *testing.T) {
+	for _, test := range []struct {
+		attrs []string
+		want  serdeAttributes
+	}{
+		{
+			attrs: []string{"#[non_exhaustive]"},
+			want:  serdeAttributes{},
+		},
+		{
+			attrs: []string{`#[serde(rename_all = "camelCase")]`},
+			want:  serdeAttributes{RenameAll: "camelCase"},
+		},
+		{
+			attrs: []string{`#[serde(rename = "@type", alias = "type", alias = "kind")]`},
+			want:  serdeAttributes{Rename: "@type", Aliases: []string{"type", "kind"}},
+		},
+		{
+			attrs: []string{`#[serde(rename(serialize = "ser", deserialize = "de"))]`},
+			want:  serdeAttributes{Rename: "ser"},
+		},
+		{
+			attrs: []string{`#[serde(skip_serializing_if = "Option::is_none")]`, "#[serde(flatten)]"},
+			want:  serdeAttributes{SkipSerializingIf: "Option::is_none", Flatten: true},
+		},
+		{
+			attrs: []string{"#[serde(skip)]", "#[serde(skip_serializing)]"},
+			want:  serdeAttributes{Skip: true, SkipSerializing: true},
+		},
+	} {
+		got := parseSerdeAttributes(test.attrs)
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("mismatch in parseSerdeAttributes(%q) (-want, +got)\n:%s", test.attrs, diff)
+		}
+	}
+}
+
+func TestApplyRenameRule(t *testing.T) {
+	for _, test := range []struct {
+		rule      string
+		name      string
+		isVariant bool
+		want      string
+	}{
+		{"lowercase", "key_id", false, "keyid"},
+		{"UPPERCASE", "key_id", false, "KEYID"},
+		{"PascalCase", "key_id", false, "KeyId"},
+		{"camelCase", "key_id", false, "keyId"},
+		{"snake_case", "key_id", false, "key_id"},
+		{"SCREAMING_SNAKE_CASE", "key_id", false, "KEY_ID"},
+		{"kebab-case", "key_id", false, "key-id"},
+		{"SCREAMING-KEBAB-CASE", "key_id", false, "KEY-ID"},
+		{"lowercase", "ServiceAccount", true, "serviceaccount"},
+		{"UPPERCASE", "ServiceAccount", true, "SERVICEACCOUNT"},
+		{"PascalCase", "ServiceAccount", true, "ServiceAccount"},
+		{"camelCase", "ServiceAccount", true, "serviceAccount"},
+		{"snake_case", "ServiceAccount", true, "service_account"},
+		{"SCREAMING_SNAKE_CASE", "ServiceAccount", true, "SERVICE_ACCOUNT"},
+		{"kebab-case", "ServiceAccount", true, "service-account"},
+		{"SCREAMING-KEBAB-CASE", "ServiceAccount", true, "SERVICE-ACCOUNT"},
+		{"unknown", "key_id", false, "key_id"},
+	} {
+		got := applyRenameRule(test.rule, test.name, test.isVariant)
+		if got != test.want {
+			t.Errorf("applyRenameRule(%q, %q, %v) = %q, want = %q", test.rule, test.name, test.isVariant, got, test.want)
+		}
+	}
+}
+
+func TestSerdeNotes(t *testing.T) {
+	camelCase := serdeAttributes{RenameAll: "camelCase"}
+	for _, test := range []struct {
+		name      string
+		item      serdeAttributes
+		container serdeAttributes
+		want      string
+	}{
+		{"key_id", serdeAttributes{}, serdeAttributes{}, ""},
+		{"key_id", serdeAttributes{}, camelCase, "Wire name: `keyId`"},
+		{"key_id", serdeAttributes{Rename: "id"}, camelCase, "Wire name: `id`"},
+		{"key_id", serdeAttributes{Aliases: []string{"key"}}, camelCase, "Wire name: `keyId` (also accepts `key`)"},
+		{"key_id", serdeAttributes{Aliases: []string{"key"}}, serdeAttributes{}, "Also accepts `key`"},
+		{"key_id", serdeAttributes{Skip: true}, serdeAttributes{}, "Skipped during serialization and deserialization."},
+		{"key_id", serdeAttributes{SkipSerializing: true}, serdeAttributes{}, "Skipped during serialization."},
+		{
+			"key_id", serdeAttributes{SkipSerializingIf: "String::is_empty"}, camelCase,
+			"Wire name: `keyId`\n\nSkipped during serialization if `String::is_empty` returns `true`.",
+		},
+	} {
+		got := serdeNotes(test.name, test.item, test.container, false)
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("mismatch in serdeNotes(%q, %v, %v) (-want, +got)\n:%s", test.name, test.item, test.container, diff)
+		}
+	}
+}
+
+func TestReadWireNames(t *testing.T) {
+	got, err := readWireNames("../../../src/generated/cloud/secretmanager/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{
+		"Secret::name":                       "name",
+		"Secret::create_time":                "createTime",
+		"Secret::version_aliases":            "versionAliases",
+		"replication::UserManaged::replicas": "replicas",
+		"secret::expire_time":                "expireTime",
+		"replication::user_managed":          "userManaged",
+	} {
+		if got[key] != want {
+			t.Errorf("mismatch in wire name for %s, want=%q, got=%q", key, want, got[key])
+		}
+	}
+
+	if got, err := readWireNames(t.TempDir()); err != nil || got != nil {
+		t.Errorf("expected no wire names without a generated serializer, got=%v, err=%v", got, err)
+	}
+}
+
+func TestGeneratedWireNames(t *testing.T) {
+	input, err := testDataPublicCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if input.WireNames, err = readWireNames("../../../src/generated/cloud/security/publicca/v1"); err != nil {
+		t.Fatal(err)
+	}
+	uid := "struct.google_cloud_security_publicca_v1.model.ExternalAccountKey"
+	outDir := t.TempDir()
+	if err := renderReference(input, findIdByUid(t, input, uid), outDir); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := os.ReadFile(filepath.Join(outDir, uid+".yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Wire name: `keyId`", "Wire name: `b64MacKey`"} {
+		if !strings.Contains(string(contents), want) {
+			t.Errorf("missing %q in the generated page:\n%s", want, contents)
+		}
+	}
+}
+
+func TestGeneratedWireNameLookup(t *testing.T) {
+	input := &crate{
+		Index: map[string]item{
+			"1": {Name: "Secret"},
+			"2": {Name: "create_time"},
+			"3": {Name: "Expiration"},
+			"4": {Name: "ExpireTime"},
+			"5": {Name: "Client"},
+			"6": {Name: "r#type"},
+		},
+		Paths: map[string]itemSummary{
+			"1": {Path: []string{"google_cloud_secretmanager_v1", "model", "Secret"}},
+			"3": {Path: []string{"google_cloud_secretmanager_v1", "model", "secret", "Expiration"}},
+			"5": {Path: []string{"google_cloud_secretmanager_v1", "client", "Client"}},
+		},
+		WireNames: map[string]string{
+			"Secret::create_time": "createTime",
+			"Secret::type":        "type",
+			"secret::expire_time": "expireTime",
+		},
+	}
+	for _, test := range []struct {
+		id, containerId string
+		isVariant       bool
+		want            string
+	}{
+		{"2", "1", false, "createTime"},
+		{"6", "1", false, "type"},
+		{"4", "3", true, "expireTime"},
+		{"2", "5", false, ""},
+		{"4", "1", true, ""},
+	} {
+		if got := input.generatedWireName(test.id, test.containerId, test.isVariant); got != test.want {
+			t.Errorf("generatedWireName(%s, %s) = %q, want = %q", test.id, test.containerId, got, test.want)
+		}
+	}
+}
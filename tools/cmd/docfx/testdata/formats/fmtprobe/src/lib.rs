@@ -0,0 +1,46 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//! A small crate to probe the rustdoc JSON format.
+
+/// A secret.
+#[non_exhaustive]
+pub struct Secret {
+    /// The secret name.
+    pub name: String,
+}
+
+/// A unit struct.
+pub struct Unit;
+
+/// A tuple struct.
+pub struct Tuple(pub i32);
+
+impl Secret {
+    /// Creates a secret.
+    pub fn new(name: &str) -> Self {
+        Self { name: name.to_string() }
+    }
+}
+
+/// A module.
+pub mod inner {
+    /// A trait.
+    pub trait Named {
+        /// Returns the name.
+        fn name(&self) -> String;
+        /// Has a default.
+        fn kind(&self) -> &'static str { "named" }
+    }
+}
@@ -24,13 +24,42 @@ import (
 //
 // Based off https://dotnet.github.io/docfx/docs/table-of-contents.html#reference-tocs
 type docfxTableOfContent struct {
-	Name    string
-	Uid     string
-	Modules []*docfxTableOfContent
-	Traits  []*docfxTableOfContent
-	Structs []*docfxTableOfContent
-	Enums   []*docfxTableOfContent
-	Aliases []*docfxTableOfContent
+	Name string
+	Uid  string
+	// Href links to pages outside the generated documentation. Only used for
+	// entries without an Uid.
+	Href string
+	// Overview links to the conceptual overview page, if any.
+	Overview *docfxTableOfContent
+	// The sections used for generated crates, see `regroupGapicTOC()`.
+	Clients         []*docfxTableOfContent
+	RequestBuilders []*docfxTableOfContent
+	Model           *docfxTableOfContent
+	Errors          []*docfxTableOfContent
+	Modules         []*docfxTableOfContent
+	Traits          []*docfxTableOfContent
+	Structs         []*docfxTableOfContent
+	Enums           []*docfxTableOfContent
+	Aliases         []*docfxTableOfContent
+	Functions       []*docfxTableOfContent
+}
+
+// HasClients returns true if the TOC has clients, the mustache templates use
+// this to avoid empty sections.
+func (toc *docfxTableOfContent) HasClients() bool {
+	return len(toc.Clients) != 0
+}
+
+// HasRequestBuilders returns true if the TOC has request builders, the
+// mustache templates use this to avoid empty sections.
+func (toc *docfxTableOfContent) HasRequestBuilders() bool {
+	return len(toc.RequestBuilders) != 0
+}
+
+// HasErrors returns true if the TOC has error types, the mustache templates
+// use this to avoid empty sections.
+func (toc *docfxTableOfContent) HasErrors() bool {
+	return len(toc.Errors) != 0
 }
 
 // HasModules returns true if the TOC has modules, the mustache templates use
@@ -63,10 +92,18 @@ func (toc *docfxTableOfContent) HasAliases() bool {
 	return len(toc.Aliases) != 0
 }
 
+// HasFunctions returns true if the TOC has free functions, the mustache
+// templates use this to avoid empty sections.
+func (toc *docfxTableOfContent) HasFunctions() bool {
+	return len(toc.Functions) != 0
+}
+
 // HasItems returns true if the TOC has any kind of item, the mustache templates
 // use this to avoid empty sections.
 func (toc *docfxTableOfContent) HasItems() bool {
-	return toc.HasModules() || toc.HasTraits() || toc.HasStructs() || toc.HasEnums() || toc.HasAliases()
+	return toc.Overview != nil || toc.HasClients() || toc.HasRequestBuilders() || toc.Model != nil || toc.HasErrors() ||
+		toc.HasModules() || toc.HasTraits() || toc.HasStructs() || toc.HasEnums() || toc.HasAliases() ||
+		toc.HasFunctions()
 }
 
 func computeTOC(crate *crate) (*docfxTableOfContent, error) {
@@ -80,8 +117,9 @@ func computeTOC(crate *crate) (*docfxTableOfContent, error) {
 	// entry in the right place.
 	rootName := crate.getRootName()
 	toc := &docfxTableOfContent{
-		Name: rootName,
-		Uid:  rootUid,
+		Name:     rootName,
+		Uid:      rootUid,
+		Overview: &docfxTableOfContent{Name: "Overview", Href: overviewFile},
 	}
 	items := map[string]*docfxTableOfContent{
 		rootName: toc,
@@ -155,7 +193,18 @@ func computeTOC(crate *crate) (*docfxTableOfContent, error) {
 				return nil, err
 			}
 			parent.Aliases = append(parent.Aliases, entry)
-		case functionKind, structFieldKind, variantKind, useKind, assocTypeKind, assocConstKind, strippedModuleKind, implKind:
+		case functionKind:
+			if crate.Paths[id].Kind != "function" {
+				// Methods and associated functions are documented as part
+				// of their containing type.
+				continue
+			}
+			parent, entry, err := insertItem(id)
+			if err != nil {
+				return nil, err
+			}
+			parent.Functions = append(parent.Functions, entry)
+		case structFieldKind, variantKind, useKind, assocTypeKind, assocConstKind, strippedModuleKind, implKind, macroKind:
 			// We do not generate an toc item for these. They should be
 			// documented as part of their containing type or module.
 			continue
@@ -175,6 +224,10 @@ func computeTOC(crate *crate) (*docfxTableOfContent, error) {
 		slices.SortStableFunc(entry.Structs, less)
 		slices.SortStableFunc(entry.Enums, less)
 		slices.SortStableFunc(entry.Aliases, less)
+		slices.SortStableFunc(entry.Functions, less)
+	}
+	if crate.useGapicTOC() {
+		regroupGapicTOC(crate, toc)
 	}
 	return toc, nil
 }
@@ -17,6 +17,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"strconv"
 	"strings"
 )
@@ -28,9 +29,50 @@ type crate struct {
 	Name     string
 	Version  string
 	Location string
-	Root     Id
-	Index    map[string]item
-	Paths    map[string]itemSummary
+	// ProjectRoot is the top-level directory of the repository. The local
+	// files referenced in the documentation must be within this directory,
+	// or within `Location` if empty.
+	ProjectRoot    string `json:"-"`
+	Root           Id
+	Index          map[string]item
+	Paths          map[string]itemSummary
+	ExternalCrates map[string]externalCrate `json:"external_crates"`
+	// PackageMetadata contains the `[package.metadata]` tables in the
+	// `Cargo.toml` file. Only available with `cargo metadata` discovery.
+	PackageMetadata map[string]json.RawMessage `json:"-"`
+	// Dependencies contains the names of the normal (not dev or build)
+	// dependencies. Only available with `cargo metadata` discovery.
+	Dependencies []string `json:"-"`
+	// AvailableFeatures contains the names of the features defined by the
+	// crate. Only available with `cargo metadata` discovery.
+	AvailableFeatures []string `json:"-"`
+	// Metadata is loaded from the `.repo-metadata.json` file, if any.
+	Metadata *repoMetadata `json:"-"`
+	// WireNames contains the proto JSON names of the fields in the generated
+	// model types, if any. See `readWireNames`.
+	WireNames map[string]string `json:"-"`
+	// HeadingBase is the level for the top-level headings in docstrings. Uses
+	// `defaultHeadingBase` if zero.
+	HeadingBase int `json:"-"`
+	// Config contains the overrides from the configuration file and the
+	// `[package.metadata.docfx]` table.
+	Config crateConfig `json:"-"`
+	// Jobs is the number of pages rendered concurrently. Renders the pages
+	// sequentially if zero.
+	Jobs int `json:"-"`
+	// logger receives the messages about this crate, uses the default logger
+	// if nil.
+	logger *slog.Logger
+	// assets records the local files copied into the output, if not nil.
+	assets *assetSources
+}
+
+// log returns the logger for messages about this crate.
+func (c *crate) log() *slog.Logger {
+	if c == nil || c.logger == nil {
+		return slog.Default()
+	}
+	return c.logger
 }
 
 func (c *crate) getRootName() string {
@@ -94,6 +136,9 @@ func (c *crate) getKind(id string) kind {
 	if c.Index[id].Inner.AssocConst != nil {
 		return assocConstKind
 	}
+	if c.Index[id].Inner.Macro != nil {
+		return macroKind
+	}
 	return undefinedKind
 }
 
@@ -101,8 +146,30 @@ func (c *crate) getName(id string) string {
 	return c.Index[id].Name
 }
 
-func (c *crate) getDocString(id string) (string, error) {
-	return processDocString(c.Index[id].Docs)
+func (c *crate) getDocString(ctx *docContext, id string) (string, error) {
+	return processDocStringWithContext(c.Index[id].Docs, ctx)
+}
+
+// newDocContext returns a context to process the docstrings in the page for
+// `id`.
+func (c *crate) newDocContext(id string) *docContext {
+	base := c.HeadingBase
+	if base == 0 {
+		base = defaultHeadingBase
+	}
+	boundary := c.ProjectRoot
+	if boundary == "" {
+		boundary = c.Location
+	}
+	return &docContext{
+		HeadingBase: base,
+		Root:        c.Location,
+		Boundary:    boundary,
+		crate:       c,
+		pageId:      id,
+		anchors:     map[string]bool{},
+		assets:      map[string]string{},
+	}
 }
 
 type kind int
@@ -123,6 +190,7 @@ const (
 	useKind
 	assocTypeKind
 	assocConstKind
+	macroKind
 )
 
 var kindName = map[kind]string{
@@ -141,6 +209,7 @@ var kindName = map[kind]string{
 	useKind:            "use",
 	assocTypeKind:      "assoc_type",
 	assocConstKind:     "assoc_const",
+	macroKind:          "macro",
 }
 
 // String returns the string representation of a `kind` constant.
@@ -153,15 +222,59 @@ type item struct {
 	Name  string
 	Docs  string
 	Inner itemEnum
-	Attrs []string
+	Attrs attributes
+}
+
+// attributes holds the attributes of an item, formatted as in the source, for
+// example `#[non_exhaustive]`.
+//
+// The rustdoc JSON uses an object for some attributes, e.g.
+// `{"other": "#[serde(skip)]"}`, and a bare string for others, e.g.
+// `"non_exhaustive"`.
+type attributes []string
+
+// UnmarshalJSON decodes the attributes of an item.
+func (a *attributes) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*a = nil
+	for _, r := range raw {
+		var s string
+		if err := json.Unmarshal(r, &s); err == nil {
+			*a = append(*a, fmt.Sprintf("#[%s]", s))
+			continue
+		}
+		var o map[string]json.RawMessage
+		if err := json.Unmarshal(r, &o); err != nil {
+			return fmt.Errorf("unexpected attribute %s: %w", r, err)
+		}
+		for k, v := range o {
+			if k == "other" {
+				if err := json.Unmarshal(v, &s); err != nil {
+					return fmt.Errorf("unexpected attribute %s: %w", r, err)
+				}
+				*a = append(*a, s)
+				continue
+			}
+			*a = append(*a, fmt.Sprintf("#[%s]", k))
+		}
+	}
+	return nil
 }
 
 type itemSummary struct {
-	CrateId Id
+	CrateId Id `json:"crate_id"`
 	Kind    string
 	Path    []string
 }
 
+type externalCrate struct {
+	Name        string
+	HtmlRootUrl *string `json:"html_root_url"`
+}
+
 type itemEnum struct {
 	Module      *module
 	Trait       *trait
@@ -175,6 +288,8 @@ type itemEnum struct {
 	Use         *use
 	AssocType   *assocType  `json:"assoc_type"`
 	AssocConst  *assocConst `json:"assoc_const"`
+	// Macro contains the source of a `macro_rules!` definition.
+	Macro *string
 }
 
 type module struct {
@@ -198,7 +313,10 @@ type variant struct {
 }
 
 type use struct {
-	// Identionally left blank.
+	Source string
+	Name   string
+	Id     *Id
+	IsGlob bool `json:"is_glob"`
 }
 
 type assocType struct {
@@ -258,10 +376,34 @@ type structInner struct {
 	Impls []Id
 }
 
+// structInnerKind is one of `"unit"`, `{"tuple": [...]}`, or
+// `{"plain": {...}}` in the rustdoc JSON.
 type structInnerKind struct {
+	Unit  bool `json:"-"`
+	Tuple []*Id
 	Plain plain
 }
 
+// UnmarshalJSON decodes the kind of a struct, including unit structs.
+func (k *structInnerKind) UnmarshalJSON(data []byte) error {
+	var unit string
+	if err := json.Unmarshal(data, &unit); err == nil {
+		if unit != "unit" {
+			return fmt.Errorf("unknown struct kind %q", unit)
+		}
+		*k = structInnerKind{Unit: true}
+		return nil
+	}
+	// Use a different type to avoid infinite recursion.
+	type structInnerKindObject structInnerKind
+	var object structInnerKindObject
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+	*k = structInnerKind(object)
+	return nil
+}
+
 type plain struct {
 	Fields []Id
 }
@@ -588,10 +730,6 @@ func getWorkspaceCrates(jsonBytes []byte) ([]crate, error) {
 	return crates, nil
 }
 
-func unmarshalRustdoc(crate *crate, jsonBytes []byte) {
-	_ = json.Unmarshal(jsonBytes, &crate)
-}
-
 func idToString(id Id) string {
 	return strconv.FormatUint(uint64(id), 10)
 }
@@ -15,6 +15,7 @@
 package main
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -45,3 +46,46 @@ func TestFunctionToString(t *testing.T) {
 		t.Errorf("bad string for function (-want, +got)\n:%s", diff)
 	}
 }
+
+func TestAttributesUnmarshal(t *testing.T) {
+	input := `["non_exhaustive", {"other": "#[serde(rename_all = \"camelCase\")]"}, {"must_use": {"reason": null}}]`
+	var got attributes
+	if err := json.Unmarshal([]byte(input), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := attributes{"#[non_exhaustive]", `#[serde(rename_all = "camelCase")]`, "#[must_use]"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch in attributes (-want, +got)\n:%s", diff)
+	}
+}
+
+func TestStructInnerKindUnmarshal(t *testing.T) {
+	var unit structInnerKind
+	if err := json.Unmarshal([]byte(`"unit"`), &unit); err != nil {
+		t.Fatal(err)
+	}
+	if !unit.Unit {
+		t.Errorf("expected a unit struct, got=%v", unit)
+	}
+
+	var tuple structInnerKind
+	if err := json.Unmarshal([]byte(`{"tuple": [1, null]}`), &tuple); err != nil {
+		t.Fatal(err)
+	}
+	if tuple.Unit || len(tuple.Tuple) != 2 || tuple.Tuple[0] == nil || *tuple.Tuple[0] != 1 || tuple.Tuple[1] != nil {
+		t.Errorf("mismatch in tuple struct, got=%v", tuple)
+	}
+
+	var plain structInnerKind
+	if err := json.Unmarshal([]byte(`{"plain": {"fields": [3, 4], "has_stripped_fields": false}}`), &plain); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]Id{3, 4}, plain.Plain.Fields); diff != "" {
+		t.Errorf("mismatch in plain struct fields (-want, +got):\n%s", diff)
+	}
+
+	var unknown structInnerKind
+	if err := json.Unmarshal([]byte(`"other"`), &unknown); err == nil {
+		t.Errorf("expected an error for an unknown struct kind, got=%v", unknown)
+	}
+}
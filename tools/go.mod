@@ -20,4 +20,5 @@ require (
 	github.com/cbroglie/mustache v1.4.0
 	github.com/google/go-cmp v0.7.0
 	github.com/yuin/goldmark v1.7.13
+	gopkg.in/yaml.v3 v3.0.1
 )